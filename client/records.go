@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/xataio/cli/client/spec"
+)
+
+// MaxPageSize is the largest page of records the API returns.
+const MaxPageSize = 200
+
+// QueryError is returned by RecordIterator when the API rejects a query.
+type QueryError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// RecordIterator streams all the records of a table query, fetching them one
+// page at a time and following the page cursor. Only one page is held in
+// memory. Typical use:
+//
+//	it := client.NewRecordIterator(c, dbBranch, table, query, 0)
+//	for it.Next(ctx) {
+//		record := it.Record()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type RecordIterator struct {
+	client   *spec.ClientWithResponses
+	dbBranch spec.DBBranchNameParam
+	table    spec.TableNameParam
+	query    spec.QueryTableJSONRequestBody
+	pageSize int
+
+	page   []spec.Record
+	index  int
+	cursor string
+	more   bool
+	err    error
+}
+
+// NewRecordIterator returns an iterator over the records matching the query.
+// The filter, sort and columns of the query apply to all the pages, while its
+// page configuration is replaced. A pageSize of 0 uses MaxPageSize.
+func NewRecordIterator(client *spec.ClientWithResponses, dbBranch spec.DBBranchNameParam,
+	table spec.TableNameParam, query spec.QueryTableJSONRequestBody, pageSize int) *RecordIterator {
+	if pageSize <= 0 || pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	return &RecordIterator{
+		client:   client,
+		dbBranch: dbBranch,
+		table:    table,
+		query:    query,
+		pageSize: pageSize,
+		index:    -1,
+		more:     true,
+	}
+}
+
+// Next advances to the next record, fetching the next page if needed. It
+// returns false when there are no more records, when the context is done or
+// on error, which Err then returns.
+func (it *RecordIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	it.index++
+	for it.index >= len(it.page) {
+		if !it.more {
+			return false
+		}
+		if err := it.fetch(ctx); err != nil {
+			it.err = err
+			return false
+		}
+		it.index = 0
+	}
+	return true
+}
+
+// Record returns the current record.
+func (it *RecordIterator) Record() spec.Record {
+	return it.page[it.index]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *RecordIterator) Err() error {
+	return it.err
+}
+
+func (it *RecordIterator) fetch(ctx context.Context) error {
+	size := it.pageSize
+	query := spec.QueryTableJSONRequestBody{Columns: it.query.Columns, Page: &spec.PageConfig{Size: &size}}
+	if it.cursor == "" {
+		query.Filter = it.query.Filter
+		query.Sort = it.query.Sort
+	} else {
+		// the cursor carries the filter and sort of the first page
+		cursor := it.cursor
+		query.Page.After = &cursor
+	}
+
+	resp, err := it.client.QueryTableWithResponse(ctx, it.dbBranch, it.table, query)
+	if err != nil {
+		return err
+	}
+	switch {
+	case resp.JSON400 != nil:
+		return &QueryError{StatusCode: resp.StatusCode(), Message: resp.JSON400.Message}
+	case resp.JSON401 != nil:
+		return &QueryError{StatusCode: resp.StatusCode(), Message: resp.JSON401.Message}
+	case resp.JSON404 != nil:
+		return &QueryError{StatusCode: resp.StatusCode(), Message: resp.JSON404.Message}
+	case resp.StatusCode() != http.StatusOK || resp.JSON200 == nil:
+		return &QueryError{StatusCode: resp.StatusCode(), Message: "unexpected response body"}
+	}
+
+	it.page = resp.JSON200.Records
+	it.cursor = resp.JSON200.Meta.Page.Cursor
+	it.more = resp.JSON200.Meta.Page.More && it.cursor != "" && len(it.page) > 0
+	return nil
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+// pagedServer serves the records 0..total-1 of a table, in pages of the
+// requested size, with the cursor being the index of the next record.
+func pagedServer(t *testing.T, total int, requests *[]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		*requests = append(*requests, body)
+
+		page := body["page"].(map[string]interface{})
+		size := int(page["size"].(float64))
+		start := 0
+		if after, ok := page["after"].(string); ok {
+			fmt.Sscanf(after, "%d", &start)
+		}
+		end := start + size
+		if end > total {
+			end = total
+		}
+		records := []map[string]interface{}{}
+		for i := start; i < end; i++ {
+			records = append(records, map[string]interface{}{"id": fmt.Sprintf("rec_%d", i), "xata": map[string]interface{}{"version": 0}})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"meta":    map[string]interface{}{"page": map[string]interface{}{"cursor": fmt.Sprint(end), "more": end < total}},
+			"records": records,
+		})
+	}))
+}
+
+func TestRecordIterator(t *testing.T) {
+	requests := []map[string]interface{}{}
+	server := pagedServer(t, 7, &requests)
+	defer server.Close()
+	client, err := spec.NewClientWithResponses(server.URL)
+	require.NoError(t, err)
+
+	filter := spec.FilterExpression{}
+	filter.Set("name", "Alice")
+	it := NewRecordIterator(client, "db:main", "users", spec.QueryTableJSONRequestBody{Filter: &filter}, 3)
+
+	ids := []string{}
+	for it.Next(context.Background()) {
+		ids = append(ids, string(it.Record().Id))
+	}
+	require.NoError(t, it.Err())
+	require.Equal(t, []string{"rec_0", "rec_1", "rec_2", "rec_3", "rec_4", "rec_5", "rec_6"}, ids)
+
+	require.Len(t, requests, 3)
+	require.Equal(t, map[string]interface{}{"name": "Alice"}, requests[0]["filter"])
+	require.NotContains(t, requests[0]["page"], "after")
+	require.NotContains(t, requests[1], "filter")
+	require.Equal(t, "3", requests[1]["page"].(map[string]interface{})["after"])
+	require.Equal(t, "6", requests[2]["page"].(map[string]interface{})["after"])
+}
+
+func TestRecordIteratorCancel(t *testing.T) {
+	requests := []map[string]interface{}{}
+	server := pagedServer(t, 10, &requests)
+	defer server.Close()
+	client, err := spec.NewClientWithResponses(server.URL)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it := NewRecordIterator(client, "db:main", "users", spec.QueryTableJSONRequestBody{}, 2)
+	require.True(t, it.Next(ctx))
+	cancel()
+	require.False(t, it.Next(ctx))
+	require.ErrorIs(t, it.Err(), context.Canceled)
+	require.Len(t, requests, 1)
+}
+
+func TestRecordIteratorError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message":"invalid filter"}`))
+	}))
+	defer server.Close()
+	client, err := spec.NewClientWithResponses(server.URL)
+	require.NoError(t, err)
+
+	it := NewRecordIterator(client, "db:main", "users", spec.QueryTableJSONRequestBody{}, 0)
+	require.False(t, it.Next(context.Background()))
+	var queryErr *QueryError
+	require.ErrorAs(t, it.Err(), &queryErr)
+	require.Equal(t, http.StatusBadRequest, queryErr.StatusCode)
+	require.Equal(t, "invalid filter", queryErr.Message)
+}
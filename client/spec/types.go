@@ -21,6 +21,10 @@ type Schema struct {
 type Table struct {
 	Name    string   `json:"name" yaml:"name"`
 	Columns []Column `json:"columns" yaml:"columns"`
+
+	// RenamedFrom is a hint for the CLI that the table used to have this
+	// name. It is not part of the API and is removed before sending a schema.
+	RenamedFrom string `json:"renamedFrom,omitempty" yaml:"renamedFrom,omitempty"`
 }
 
 type Column struct {
@@ -43,6 +47,15 @@ type Column struct {
 	Unique   bool `json:"unique,omitempty" yaml:"unique,omitempty"`
 
 	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// DefaultValue is the value new records get when they don't set the
+	// column. It is kept as text: numbers and booleans as their literal,
+	// strings without quotes.
+	DefaultValue string `json:"defaultValue,omitempty" yaml:"defaultValue,omitempty"`
+
+	// RenamedFrom is a hint for the CLI that the column used to have this
+	// name. It is not part of the API and is removed before sending a schema.
+	RenamedFrom string `json:"renamedFrom,omitempty" yaml:"renamedFrom,omitempty"`
 }
 
 // ColumnString contains settings for the string type.
@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	xataclient "github.com/xataio/cli/client"
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/urfave/cli/v2"
+)
+
+// backupFormatVersion is the version of the archive layout, checked on
+// restore.
+const backupFormatVersion = 1
+
+const (
+	backupManifestName = "manifest.json"
+	backupSchemaName   = "schema.json"
+)
+
+// backupManifest describes the content of a backup archive.
+type backupManifest struct {
+	FormatVersion   int           `json:"formatVersion"`
+	Database        string        `json:"database"`
+	Branch          string        `json:"branch"`
+	LastMigrationID string        `json:"lastMigrationID"`
+	CreatedAt       time.Time     `json:"createdAt"`
+	Tables          []backupTable `json:"tables"`
+}
+
+type backupTable struct {
+	Name    string `json:"name"`
+	File    string `json:"file"`
+	Records int    `json:"records"`
+}
+
+func backupTableFile(table string) string {
+	return "tables/" + table + ".ndjson"
+}
+
+// archiveFormat returns the format of the archive, from the --format flag or
+// the file extension: zip, tar or tar.gz.
+func archiveFormat(filename, format string) (string, error) {
+	lower := strings.ToLower(filename)
+	gzipped := strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+	switch format {
+	case "":
+		switch {
+		case strings.HasSuffix(lower, ".zip"):
+			return "zip", nil
+		case strings.HasSuffix(lower, ".tar"):
+			return "tar", nil
+		case gzipped:
+			return "tar.gz", nil
+		}
+		return "", fmt.Errorf("Can't tell the format of %s from its extension, use --format tar or zip", filename)
+	case "zip":
+		return "zip", nil
+	case "tar":
+		if gzipped {
+			return "tar.gz", nil
+		}
+		return "tar", nil
+	}
+	return "", fmt.Errorf("Invalid --format %s: expected tar or zip", format)
+}
+
+// writeArchive writes the files of the directory, by their slash separated
+// names, in an archive of the format.
+func writeArchive(out io.Writer, format, dir string, names []string) error {
+	if format == "zip" {
+		zw := zip.NewWriter(out)
+		for _, name := range names {
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+			if err != nil {
+				return err
+			}
+			if err := copyFileTo(w, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	}
+
+	var gw *gzip.Writer
+	if format == "tar.gz" {
+		gw = gzip.NewWriter(out)
+		out = gw
+	}
+	tw := tar.NewWriter(out)
+	for _, name := range names {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime(), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if err := copyFileTo(tw, filename); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if gw != nil {
+		return gw.Close()
+	}
+	return nil
+}
+
+func copyFileTo(w io.Writer, filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+func writeJSONFile(filename string, v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(out, '\n'), 0644)
+}
+
+// backupTableRecords writes all the records of the table as NDJSON, with
+// links as the IDs of the linked records, and returns how many were written.
+func backupTableRecords(c *cli.Context, client *spec.ClientWithResponses, dbBranch spec.DBBranchNameParam,
+	table spec.Table, filename string) (int, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	buffered := bufio.NewWriter(file)
+	writer := &ndjsonRecordWriter{encoder: json.NewEncoder(buffered)}
+
+	it := xataclient.NewRecordIterator(client, dbBranch, spec.TableNameParam(table.Name), spec.QueryTableJSONRequestBody{}, 0)
+	count, err := exportRecords(c, it, table, nil, writer)
+	if err != nil {
+		return count, err
+	}
+	if err := buffered.Flush(); err != nil {
+		return count, err
+	}
+	return count, file.Close()
+}
+
+// BackupCommand writes the schema and the records of all the tables of the
+// branch to an archive.
+func BackupCommand(c *cli.Context) error {
+	dbName, _, branch, err := getDBNameAndBranch(c)
+	if err != nil {
+		return err
+	}
+	if c.IsSet("branch") {
+		branch = c.String("branch")
+	}
+	dbBranch := spec.DBBranchNameParam(fmt.Sprintf("%s:%s", dbName, branch))
+
+	out := c.String("out")
+	if out == "" {
+		extension := ".tar.gz"
+		if c.String("format") == "zip" {
+			extension = ".zip"
+		}
+		out = fmt.Sprintf("%s-%s-%s%s", dbName, branch, time.Now().UTC().Format("20060102-150405"), extension)
+	}
+	format, err := archiveFormat(out, c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+	details, err := getBranchDetails(c, dbName, branch)
+	if err != nil {
+		return err
+	}
+
+	dir, err := ioutil.TempDir("", "xata-backup")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "tables"), 0755); err != nil {
+		return err
+	}
+
+	manifest := backupManifest{
+		FormatVersion:   backupFormatVersion,
+		Database:        dbName,
+		Branch:          branch,
+		LastMigrationID: details.LastMigrationID,
+		CreatedAt:       time.Now().UTC(),
+		Tables:          []backupTable{},
+	}
+	if err := writeJSONFile(filepath.Join(dir, backupSchemaName), details.Schema); err != nil {
+		return err
+	}
+	names := []string{backupManifestName, backupSchemaName}
+	total := 0
+	for _, table := range details.Schema.Tables {
+		file := backupTableFile(table.Name)
+		count, err := backupTableRecords(c, client, dbBranch, table, filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			return fmt.Errorf("Error backing up table [%s]: %w", table.Name, err)
+		}
+		fmt.Printf("Backed up %d records of table [%s]\n", count, table.Name)
+		manifest.Tables = append(manifest.Tables, backupTable{Name: table.Name, File: file, Records: count})
+		names = append(names, file)
+		total += count
+	}
+	if err := writeJSONFile(filepath.Join(dir, backupManifestName), manifest); err != nil {
+		return err
+	}
+
+	// write next to the target and rename, so that an interrupted backup
+	// doesn't leave a truncated archive behind
+	partial := out + ".partial"
+	file, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+	err = writeArchive(file, format, dir, names)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(partial, out)
+	}
+	if err != nil {
+		os.Remove(partial)
+		return fmt.Errorf("Error writing backup archive: %w", err)
+	}
+
+	fmt.Printf("Backup of [%s] with %d records in %d tables written to %s\n", dbBranch, total, len(manifest.Tables), out)
+	return nil
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestArchiveFormat(t *testing.T) {
+	for filename, expected := range map[string]string{
+		"backup.zip":    "zip",
+		"backup.tar":    "tar",
+		"backup.tar.gz": "tar.gz",
+		"backup.TGZ":    "tar.gz",
+	} {
+		format, err := archiveFormat(filename, "")
+		require.NoError(t, err)
+		require.Equal(t, expected, format, filename)
+	}
+	format, err := archiveFormat("backup.tgz", "tar")
+	require.NoError(t, err)
+	require.Equal(t, "tar.gz", format)
+	format, err = archiveFormat("backup", "zip")
+	require.NoError(t, err)
+	require.Equal(t, "zip", format)
+	_, err = archiveFormat("backup", "")
+	require.Error(t, err)
+	_, err = archiveFormat("backup.zip", "rar")
+	require.Error(t, err)
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "tables"), 0755))
+	files := map[string]string{
+		backupManifestName:    `{"formatVersion":1}`,
+		backupSchemaName:      `{"tables":[]}`,
+		"tables/users.ndjson": "{\"id\":\"rec_1\"}\n",
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, backupManifestName), []byte(files[backupManifestName]), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, backupSchemaName), []byte(files[backupSchemaName]), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "tables", "users.ndjson"), []byte(files["tables/users.ndjson"]), 0644))
+	names := []string{backupManifestName, backupSchemaName, "tables/users.ndjson"}
+
+	for _, format := range []string{"zip", "tar", "tar.gz"} {
+		archive := filepath.Join(t.TempDir(), "backup."+format)
+		out, err := os.Create(archive)
+		require.NoError(t, err)
+		require.NoError(t, writeArchive(out, format, dir, names))
+		require.NoError(t, out.Close())
+
+		fsys, cleanup, err := openArchive(archive)
+		require.NoError(t, err, format)
+		for _, name := range names {
+			content, err := fs.ReadFile(fsys, name)
+			require.NoError(t, err, format)
+			require.Equal(t, files[name], string(content))
+		}
+		require.NoError(t, cleanup())
+	}
+
+	require.True(t, isBackupFile("tables/users.ndjson"))
+	require.False(t, isBackupFile("tables/../evil.ndjson"))
+	require.False(t, isBackupFile("tables/nested/x.ndjson"))
+	require.False(t, isBackupFile("/etc/passwd"))
+}
+
+func TestLoadOrder(t *testing.T) {
+	link := func(name, table string) spec.Column {
+		return spec.Column{Name: name, Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: table}}
+	}
+	tables := []spec.Table{
+		{Name: "posts", Columns: []spec.Column{link("author", "users"), link("parent", "posts")}},
+		{Name: "users", Columns: []spec.Column{
+			{Name: "name", Type: spec.ColumnTypeString},
+			{Name: "settings", Type: spec.ColumnTypeObject, Columns: []spec.Column{link("team", "teams")}},
+		}},
+		{Name: "teams", Columns: []spec.Column{link("owner", "owners")}},
+		{Name: "owners", Columns: []spec.Column{link("team", "teams")}},
+	}
+	order, deferred := loadOrder(tables)
+	names := []string{}
+	for _, table := range order {
+		names = append(names, table.Name)
+	}
+	require.Equal(t, []string{"teams", "users", "posts", "owners"}, names)
+	require.Equal(t, map[string][]string{"teams": {"owner"}, "posts": {"parent"}}, deferred)
+}
+
+func TestSplitDeferred(t *testing.T) {
+	record := map[string]interface{}{"name": "a", "parent": "rec_1", "manager": nil}
+	values := splitDeferred(record, []string{"parent", "manager", "missing"})
+	require.Equal(t, map[string]interface{}{"parent": "rec_1"}, values)
+	require.Equal(t, map[string]interface{}{"name": "a"}, record)
+}
+
+func TestLoadRecords(t *testing.T) {
+	data := strings.Repeat("{\"id\":\"rec\",\"name\":\"a\"}\n", 50)
+	var mu sync.Mutex
+	sent := 0
+	count, err := loadRecords(newNDJSONRowReader(strings.NewReader(data), usersTable), 4, func(row *importRow) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 50, count)
+	require.Equal(t, 50, sent)
+
+	_, err = loadRecords(newNDJSONRowReader(strings.NewReader(data), usersTable), 4, func(row *importRow) error {
+		return errors.New("unauthorized")
+	})
+	require.EqualError(t, err, "unauthorized")
+
+	_, err = loadRecords(newNDJSONRowReader(strings.NewReader("{\"age\":\"x\"}\n"), usersTable), 1, func(row *importRow) error {
+		return nil
+	})
+	require.EqualError(t, err, "line 1: Invalid value x for column users.age of type int")
+}
@@ -34,6 +34,45 @@ func GetBranchesSubcommands() []*cli.Command {
 			Usage:  "Delete branch",
 			Action: deleteBranch,
 		},
+		{
+			Name:   "copy-data",
+			Usage:  "Copy records from one branch to another, keeping their IDs",
+			Action: copyBranchData,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "from",
+					Usage:    "The branch to copy the records from.",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "to",
+					Usage:    "The branch to copy the records to.",
+					Required: true,
+				},
+				&cli.StringSliceFlag{
+					Name:  "tables",
+					Usage: "Comma separated list of tables to copy (default: all the tables both branches have).",
+				},
+				&cli.StringFlag{
+					Name:  "where",
+					Usage: "Copy only the records matching the filter, e.g. 'created > \"2022-01-01\"'. Requires --tables, and applies to each of them.",
+				},
+				&cli.IntFlag{
+					Name:  "sample",
+					Usage: "Copy at most `N` records per table (default: all).",
+				},
+				&cli.IntFlag{
+					Name:  "batch-size",
+					Usage: "Number of records sent per request.",
+					Value: 500,
+				},
+				&cli.IntFlag{
+					Name:  "concurrency",
+					Usage: "Number of records updated in parallel to set links between records of the same table.",
+					Value: 8,
+				},
+			},
+		},
 	}
 }
 
@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	xataclient "github.com/xataio/cli/client"
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/urfave/cli/v2"
+)
+
+// recordRows reads the records of a query as rows to import, with links as
+// the IDs of the linked records. A limit greater than 0 stops after that many
+// records.
+type recordRows struct {
+	ctx   context.Context
+	it    *xataclient.RecordIterator
+	table spec.Table
+	limit int
+	read  int
+}
+
+func (r *recordRows) Read() (*importRow, error) {
+	if (r.limit <= 0 || r.read < r.limit) && r.it.Next(r.ctx) {
+		r.read++
+		record := exportValues(r.table, r.it.Record(), nil)
+		return &importRow{line: r.read, record: record, raw: record["id"]}, nil
+	}
+	if err := iteratorError(r.it.Err()); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// rowFunc adapts a function to a rowReader.
+type rowFunc func() (*importRow, error)
+
+func (f rowFunc) Read() (*importRow, error) {
+	return f()
+}
+
+// sharedColumns returns the table with only the columns that also exist in
+// the other table.
+func sharedColumns(table, other spec.Table) spec.Table {
+	shared := spec.Table{Name: table.Name, Columns: []spec.Column{}}
+	for _, column := range table.Columns {
+		if _, ok := findColumn(other.Columns, column.Name); ok {
+			shared.Columns = append(shared.Columns, column)
+		}
+	}
+	return shared
+}
+
+// linkRows reads the held back links of a table, by record ID, as rows with
+// the ID as raw value.
+func linkRows(links map[string]map[string]interface{}) rowReader {
+	ids := make([]string, 0, len(links))
+	for id := range links {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	next := 0
+	return rowFunc(func() (*importRow, error) {
+		if next == len(ids) {
+			return nil, io.EOF
+		}
+		id := ids[next]
+		next++
+		return &importRow{line: next, record: links[id], raw: id}, nil
+	})
+}
+
+// copiedIDs tracks the IDs of the records copied by table, when only some of
+// the records are copied.
+type copiedIDs map[string]map[string]bool
+
+// pruneLinks removes the links to records of the copied tables that weren't
+// copied, which would otherwise fail to resolve.
+func (copied copiedIDs) pruneLinks(columns []spec.Column, values map[string]interface{}) {
+	for name, value := range values {
+		column, ok := findColumn(columns, name)
+		if !ok {
+			continue
+		}
+		if nested, isObject := value.(map[string]interface{}); isObject && column.Type == spec.ColumnTypeObject {
+			copied.pruneLinks(column.Columns, nested)
+			continue
+		}
+		id, isString := value.(string)
+		if column.Type != spec.ColumnTypeLink || column.Link == nil || !isString {
+			continue
+		}
+		if ids, isCopied := copied[column.Link.Table]; isCopied && !ids[id] {
+			delete(values, name)
+		}
+	}
+}
+
+// copyTables picks the tables to copy: the --tables, or all the tables of the
+// source branch that the target branch has too.
+func copyTables(source, target spec.Schema, names []string, from, to string) ([]spec.Table, error) {
+	sourceTables, targetTables := tablesByName(source), tablesByName(target)
+	tables := []spec.Table{}
+	if len(names) == 0 {
+		for _, table := range source.Tables {
+			if targetTable, ok := targetTables[table.Name]; ok {
+				tables = append(tables, sharedColumns(table, targetTable))
+			} else {
+				fmt.Printf("Skipping table [%s]: it doesn't exist in branch [%s]\n", table.Name, to)
+			}
+		}
+		return tables, nil
+	}
+	for _, name := range names {
+		table, ok := sourceTables[name]
+		if !ok {
+			return nil, fmt.Errorf("Table [%s] doesn't exist in branch [%s]", name, from)
+		}
+		targetTable, ok := targetTables[name]
+		if !ok {
+			return nil, fmt.Errorf("Table [%s] doesn't exist in branch [%s]", name, to)
+		}
+		tables = append(tables, sharedColumns(table, targetTable))
+	}
+	return tables, nil
+}
+
+func copyBranchData(c *cli.Context) error {
+	dbName, _, _, err := getDBNameAndBranch(c)
+	if err != nil {
+		return err
+	}
+	from, to := c.String("from"), c.String("to")
+	for _, branch := range []string{from, to} {
+		if !spec.IsValidIdentifier(branch) {
+			return fmt.Errorf("Invalid branch name [%s]", branch)
+		}
+	}
+	if from == to {
+		return fmt.Errorf("The --from and --to branches must be different")
+	}
+	sample := c.Int("sample")
+	if sample < 0 {
+		return fmt.Errorf("Invalid --sample %d: must not be negative", sample)
+	}
+	batchSize := c.Int("batch-size")
+	if batchSize < 1 {
+		return fmt.Errorf("Invalid --batch-size %d: must be at least 1", batchSize)
+	}
+	if c.Int("concurrency") < 1 {
+		return fmt.Errorf("Invalid --concurrency %d: must be at least 1", c.Int("concurrency"))
+	}
+	var filter *spec.FilterExpression
+	if where := c.String("where"); strings.TrimSpace(where) != "" {
+		// the same filter is sent for every table, so the tables have to be
+		// picked by hand to be sure they all have the filtered columns
+		if len(c.StringSlice("tables")) == 0 {
+			return fmt.Errorf("--where applies to every copied table, so it requires --tables")
+		}
+		filter, err = CompileFilter(where)
+		if err != nil {
+			return err
+		}
+	}
+
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+	source, err := getBranchDetails(c, dbName, from)
+	if err != nil {
+		return err
+	}
+	target, err := getBranchDetails(c, dbName, to)
+	if err != nil {
+		return err
+	}
+	tables, err := copyTables(source.Schema, target.Schema, c.StringSlice("tables"), from, to)
+	if err != nil {
+		return err
+	}
+	fromBranch := spec.DBBranchNameParam(fmt.Sprintf("%s:%s", dbName, from))
+	toBranch := spec.DBBranchNameParam(fmt.Sprintf("%s:%s", dbName, to))
+
+	// with a filter or a sample, links can point to records that aren't
+	// copied: track the copied IDs to drop these links
+	var copied copiedIDs
+	if filter != nil || sample > 0 {
+		copied = copiedIDs{}
+		for _, table := range tables {
+			copied[table.Name] = map[string]bool{}
+		}
+	}
+	skipped := map[string]map[string]bool{}
+	query := func(table spec.Table) spec.QueryTableJSONRequestBody {
+		filterColumns := spec.ColumnsFilter(columnNames(table.Columns))
+		return spec.QueryTableJSONRequestBody{Filter: filter, Columns: &filterColumns}
+	}
+	// the links held back from the inserts, by table and record ID; they are
+	// kept rather than read again, as a second query with --sample could
+	// return other records
+	links := map[string]map[string]map[string]interface{}{}
+
+	order, deferred := loadOrder(tables)
+	copiedTotal, rejectedTotal := 0, 0
+	for _, table := range order {
+		name := table.Name
+		im := &recordImporter{
+			insert: bulkInserter(c, client, toBranch, name),
+			reject: func(row *importRow) error {
+				id := row.raw.(string)
+				fmt.Printf("Skipped record [%s] of table [%s]: %s\n", id, name, row.err)
+				if skipped[name] == nil {
+					skipped[name] = map[string]bool{}
+				}
+				skipped[name][id] = true
+				if copied != nil {
+					delete(copied[name], id)
+				}
+				return nil
+			},
+			batchSize: batchSize,
+		}
+		rows := &recordRows{
+			ctx:   c.Context,
+			it:    xataclient.NewRecordIterator(client, fromBranch, spec.TableNameParam(name), query(table), 0),
+			table: table,
+			limit: sample,
+		}
+		err := importRows(rowFunc(func() (*importRow, error) {
+			row, err := rows.Read()
+			if err != nil {
+				return nil, err
+			}
+			if values := splitDeferred(row.record, deferred[name]); len(values) > 0 {
+				if links[name] == nil {
+					links[name] = map[string]map[string]interface{}{}
+				}
+				links[name][row.raw.(string)] = values
+			}
+			if copied != nil {
+				copied.pruneLinks(table.Columns, row.record)
+				copied[name][row.raw.(string)] = true
+			}
+			return row, nil
+		}), im)
+		if err != nil {
+			return fmt.Errorf("Error copying table [%s]: %w", name, err)
+		}
+		fmt.Printf("Copied %d records of table [%s]\n", im.imported, name)
+		copiedTotal += im.imported
+		rejectedTotal += im.rejected
+	}
+
+	// set the links that couldn't be inserted with their records
+	for _, table := range order {
+		name := table.Name
+		if len(deferred[name]) == 0 {
+			continue
+		}
+		_, err := loadRecords(linkRows(links[name]), c.Int("concurrency"), func(row *importRow) error {
+			id := row.raw.(string)
+			if skipped[name][id] || (copied != nil && !copied[name][id]) {
+				return nil
+			}
+			if copied != nil {
+				copied.pruneLinks(table.Columns, row.record)
+			}
+			if len(row.record) == 0 {
+				return nil
+			}
+			return updateWithID(c, client, toBranch, name, id, row.record)
+		})
+		if err != nil {
+			return fmt.Errorf("Error copying the links of table [%s]: %w", name, err)
+		}
+		fmt.Printf("Copied the links of table [%s]: %s\n", name, strings.Join(deferred[name], ", "))
+	}
+
+	fmt.Printf("Copied %d records in %d tables from [%s] to [%s]\n", copiedTotal, len(order), fromBranch, toBranch)
+	if rejectedTotal > 0 {
+		return cli.Exit(fmt.Sprintf("%d records were skipped", rejectedTotal), 1)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestCopyTables(t *testing.T) {
+	source := spec.Schema{Tables: []spec.Table{
+		usersTable,
+		{Name: "teams", Columns: []spec.Column{{Name: "name", Type: spec.ColumnTypeString}}},
+	}}
+	target := spec.Schema{Tables: []spec.Table{
+		{Name: "users", Columns: []spec.Column{
+			{Name: "name", Type: spec.ColumnTypeString},
+			{Name: "team", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "teams"}},
+			{Name: "nickname", Type: spec.ColumnTypeString},
+		}},
+	}}
+
+	tables, err := copyTables(source, target, nil, "main", "feature")
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	require.Equal(t, "users", tables[0].Name)
+	require.Equal(t, []string{"name", "team"}, columnNames(tables[0].Columns))
+
+	_, err = copyTables(source, target, []string{"teams"}, "main", "feature")
+	require.EqualError(t, err, "Table [teams] doesn't exist in branch [feature]")
+	_, err = copyTables(source, target, []string{"posts"}, "main", "feature")
+	require.EqualError(t, err, "Table [posts] doesn't exist in branch [main]")
+}
+
+func TestPruneLinks(t *testing.T) {
+	columns := []spec.Column{
+		{Name: "team", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "teams"}},
+		{Name: "owner", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "owners"}},
+		{Name: "settings", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+			{Name: "team", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "teams"}},
+		}},
+	}
+	copied := copiedIDs{"teams": {"rec_a": true}}
+
+	values := map[string]interface{}{"team": "rec_a", "owner": "rec_o", "settings": map[string]interface{}{"team": "rec_b"}}
+	copied.pruneLinks(columns, values)
+	// owners aren't copied, so their links are kept
+	require.Equal(t, map[string]interface{}{"team": "rec_a", "owner": "rec_o", "settings": map[string]interface{}{}}, values)
+
+	values = map[string]interface{}{"team": "rec_b"}
+	copied.pruneLinks(columns, values)
+	require.Empty(t, values)
+}
+
+func TestLinkRows(t *testing.T) {
+	record := map[string]interface{}{"id": "rec_1", "name": "Alice", "manager": "rec_2"}
+	links := map[string]map[string]map[string]interface{}{}
+	links["users"] = map[string]map[string]interface{}{}
+	links["users"]["rec_1"] = splitDeferred(record, []string{"manager", "mentor"})
+	links["users"]["rec_0"] = map[string]interface{}{"manager": "rec_1"}
+
+	require.Equal(t, map[string]interface{}{"id": "rec_1", "name": "Alice"}, record)
+	all := readAllRows(t, linkRows(links["users"]))
+	require.Len(t, all, 2)
+	require.Equal(t, "rec_0", all[0].raw)
+	require.Equal(t, map[string]interface{}{"manager": "rec_1"}, all[0].record)
+	require.Equal(t, "rec_1", all[1].raw)
+	require.Equal(t, map[string]interface{}{"manager": "rec_2"}, all[1].record)
+
+	require.Empty(t, readAllRows(t, linkRows(links["teams"])))
+}
@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path"
+	"strings"
 	"time"
 
 	"github.com/xataio/cli/client"
@@ -14,7 +14,9 @@ import (
 	"github.com/xataio/cli/config"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
 	"github.com/gosimple/slug"
+	"github.com/tidwall/pretty"
 	"github.com/urfave/cli/v2"
 	"gopkg.in/yaml.v3"
 )
@@ -22,6 +24,10 @@ import (
 const defaultBranchName = "main"
 
 func DeployCommand(c *cli.Context) error {
+	if c.Bool("check") {
+		return deployCheck(c)
+	}
+
 	force := c.Bool("force")
 	if interactive, reason := isInteractiveWithReason(c); !force && !interactive {
 		return fmt.Errorf("The deploy command is interactive but %s. Use --force to deploy without asking for confirmation.", reason)
@@ -123,63 +129,149 @@ func DeployCommand(c *cli.Context) error {
 		}
 	}
 
-	dbBranchName := spec.DBBranchNameParam(fmt.Sprintf("%s:%s", dbName, branch))
-	cr := spec.ClientWithResponses{ClientInterface: client}
-	resp, err := cr.GetBranchMigrationPlanWithResponse(c.Context,
-		dbBranchName,
-		spec.GetBranchMigrationPlanJSONRequestBody(schema))
+	plan, _, err := planDeploy(c, dbName, branch, schema, !force)
 	if err != nil {
-		return fmt.Errorf("Error getting migration plan: %w", err)
-	}
-	if resp.StatusCode() > 299 {
-		return fmt.Errorf("Error getting migration plan: %s", resp.Status())
+		return err
 	}
 
-	plan := resp.JSON200
-
-	// Is plan empty
-	if (plan.Migration.NewTables == nil || len(plan.Migration.NewTables.AdditionalProperties) == 0) &&
-		plan.Migration.RemovedTables == nil &&
-		plan.Migration.TableMigrations == nil {
+	if isMigrationEmpty(plan.Migration) {
 		fmt.Println("Your schema is up to date.")
 		return nil
 	}
 
-	fmt.Printf("Migration plan preview:\n\n")
-	PrintMigration(plan.Migration, c.Bool("nocolor"))
-	fmt.Println()
+	settings, err := ReadSettings(dir)
+	if err != nil {
+		return err
+	}
+	if err := checkDestructivePolicy(settings, branch, plan.Migration, force, c.Bool("allow-destructive")); err != nil {
+		return err
+	}
+
+	previewMigration(c, plan.Migration)
 
-	var yes bool
+	yes := force
 	if !force {
 		prompt := &survey.Confirm{
 			Message: "Apply the above migration?",
-			Default: true,
+			Default: len(destructiveChanges(plan.Migration)) == 0,
 		}
 		survey.AskOne(prompt, &yes)
 	}
 
 	if yes {
-		sha, _ := GitGetLastSHA()
-		if sha != "" {
-			localChanges, _ := GitHasLocalChanges(schemaFile)
-			plan.Migration.LastGitRevision = &sha
-			plan.Migration.LocalChanges = localChanges
+		setMigrationGitRevision(&plan.Migration, schemaFile)
+		if err := executeMigrationPlan(c, dbName, branch, plan); err != nil {
+			return err
 		}
-		now := spec.DateTime(time.Now())
-		plan.Migration.CreatedAt = &now
-		mResp, err := cr.ExecuteBranchMigrationPlanWithResponse(c.Context,
-			dbBranchName,
-			spec.ExecuteBranchMigrationPlanJSONRequestBody(*plan))
+		fmt.Println("Done.")
+	}
+
+	return nil
+}
+
+// planDeploy asks the server for the plan that migrates the branch to the
+// schema, and fixes up the renames the server can't know about. It also
+// returns the branch details the plan was made against.
+func planDeploy(c *cli.Context, dbName, branch string, schema spec.Schema, interactive bool) (*spec.BranchMigrationPlan, *spec.DBBranch, error) {
+	if err := checkRenameHints(schema); err != nil {
+		return nil, nil, err
+	}
+
+	// get the details first: if the branch changes in between, the plan is
+	// newer than LastMigrationID, which errs on the safe side
+	details, err := getBranchDetails(c, dbName, branch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.GetBranchMigrationPlanWithResponse(c.Context,
+		spec.DBBranchNameParam(fmt.Sprintf("%s:%s", dbName, branch)),
+		spec.GetBranchMigrationPlanJSONRequestBody(stripRenameHints(schema)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error getting migration plan: %w", err)
+	}
+	if resp.StatusCode() > 299 {
+		return nil, nil, fmt.Errorf("Error getting migration plan: %s", resp.Status())
+	}
+	plan := spec.BranchMigrationPlan(*resp.JSON200)
+
+	// the server matches tables and columns by name, so renames come back as
+	// a removal plus an addition, which would lose the data
+	tableRenames, columnRenames := renameHints(schema)
+	applyRenames(&plan.Migration, details.Schema, schema, tableRenames, columnRenames)
+
+	// a mismatch means one of the two plans is wrong about what changes,
+	// which is worth stopping for
+	if !c.Bool("no-verify-plan") {
+		localPlan := PlanMigration(details.Schema, schema)
+		if diffs := compareMigrations("local", localPlan, "server", plan.Migration); diffs != nil {
+			return nil, nil, fmt.Errorf("The migration plan from the server doesn't match the local plan:\n  %s", strings.Join(diffs, "\n  "))
+		}
+	}
+
+	if interactive && hasRemovals(plan.Migration) {
+		tables, err := confirmTableRenames(detectTableRenames(plan.Migration, details.Schema, schema))
 		if err != nil {
-			return fmt.Errorf("Error executing migration: %w", err)
+			return nil, nil, err
 		}
-		if mResp.StatusCode() > 299 {
-			return fmt.Errorf("Error executing migration: %s", mResp.Status())
+		applyRenames(&plan.Migration, details.Schema, schema, tables, nil)
+		columns, err := confirmColumnRenames(detectColumnRenames(plan.Migration, details.Schema, schema))
+		if err != nil {
+			return nil, nil, err
 		}
+		applyRenames(&plan.Migration, details.Schema, schema, nil, columns)
+	}
 
-		fmt.Println("Done.")
+	return &plan, details, nil
+}
+
+// previewMigration prints the migration, with a warning if it loses data.
+func previewMigration(c *cli.Context, migration spec.BranchMigration) {
+	fmt.Printf("Migration plan preview:\n\n")
+	PrintMigration(migration, c.Bool("nocolor"))
+	fmt.Println()
+
+	if destructive := destructiveChanges(migration); len(destructive) > 0 {
+		red := color.New(color.FgRed)
+		if c.Bool("nocolor") {
+			red.DisableColor()
+		}
+		red.Printf("Warning: the migration would lose data:\n  %s\n\n", strings.Join(destructive, "\n  "))
+	}
+}
+
+// setMigrationGitRevision records the git commit the schema file comes from
+// in the migration, if the project is in a git repository.
+func setMigrationGitRevision(migration *spec.BranchMigration, schemaFile string) {
+	sha, _ := GitGetLastSHA()
+	if sha != "" {
+		localChanges, _ := GitHasLocalChanges(schemaFile)
+		migration.LastGitRevision = &sha
+		migration.LocalChanges = localChanges
 	}
+}
 
+func executeMigrationPlan(c *cli.Context, dbName, branch string, plan *spec.BranchMigrationPlan) error {
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+
+	now := spec.DateTime(time.Now())
+	plan.Migration.CreatedAt = &now
+	resp, err := client.ExecuteBranchMigrationPlanWithResponse(c.Context,
+		spec.DBBranchNameParam(fmt.Sprintf("%s:%s", dbName, branch)),
+		spec.ExecuteBranchMigrationPlanJSONRequestBody(*plan))
+	if err != nil {
+		return fmt.Errorf("Error executing migration: %w", err)
+	}
+	if resp.StatusCode() > 299 {
+		return fmt.Errorf("Error executing migration: %s", resp.Status())
+	}
 	return nil
 }
 
@@ -190,10 +282,7 @@ func readSchemaFile(dir string) (spec.Schema, string, error) {
 		return spec.Schema{}, "", err
 	}
 
-	schemaFile := path.Join(dir, "schema.json")
-	if settings.SchemaFileFormat == SettingsYAML {
-		schemaFile = path.Join(dir, "schema.yaml")
-	}
+	schemaFile := SchemaFilePath(dir, settings)
 
 	jsonFile, err := os.Open(schemaFile)
 	if err != nil {
@@ -209,20 +298,53 @@ func readSchemaFile(dir string) (spec.Schema, string, error) {
 		return spec.Schema{}, "", fmt.Errorf("reading file: %w", err)
 	}
 
+	schema, err := unmarshalSchema(settings.SchemaFileFormat, schemaFile, bytes)
+	if err != nil {
+		return spec.Schema{}, "", err
+	}
+
+	return schema, schemaFile, nil
+}
+
+// unmarshalSchema decodes a schema file in the given format. The filename is
+// only used in error messages.
+func unmarshalSchema(format, filename string, bytes []byte) (spec.Schema, error) {
 	var schema spec.Schema
-	if settings.SchemaFileFormat == SettingsYAML {
-		err = yaml.Unmarshal(bytes, &schema)
+	switch format {
+	case SettingsYAML:
+		err := yaml.Unmarshal(bytes, &schema)
 		if err != nil {
-			return spec.Schema{}, "", fmt.Errorf("unmarshaling: %w", err)
+			return spec.Schema{}, fmt.Errorf("unmarshaling: %w", err)
 		}
-	} else {
-		err = json.Unmarshal(bytes, &schema)
+	case SettingsXata:
+		var err error
+		schema, err = ParseSchemaDSL(filename, bytes)
 		if err != nil {
-			return spec.Schema{}, "", fmt.Errorf("unmarshaling: %w", err)
+			return spec.Schema{}, fmt.Errorf("parsing: %w", err)
+		}
+	default:
+		err := json.Unmarshal(bytes, &schema)
+		if err != nil {
+			return spec.Schema{}, fmt.Errorf("unmarshaling: %w", err)
 		}
 	}
+	return schema, nil
+}
 
-	return schema, schemaFile, nil
+// marshalSchema encodes a schema in the given format.
+func marshalSchema(format string, schema spec.Schema) ([]byte, error) {
+	switch format {
+	case SettingsYAML:
+		return yaml.Marshal(schema)
+	case SettingsXata:
+		return FormatSchemaDSL(schema), nil
+	default:
+		file, err := json.MarshalIndent(schema, "", " ")
+		if err != nil {
+			return nil, err
+		}
+		return pretty.Pretty(file), nil
+	}
 }
 
 func getDBNameAndBranch(c *cli.Context) (dbName, repo, branch string, err error) {
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/xataio/cli/client"
+	"github.com/xataio/cli/client/spec"
+	"github.com/xataio/cli/config"
+	"github.com/xataio/cli/filesystem"
+
+	"github.com/urfave/cli/v2"
+)
+
+// deployCheckPending is the exit code of `deploy --check` when changes are
+// pending. Errors exit with 1, like every command.
+const deployCheckPending = 2
+
+// DeploySummary is the machine readable result of `deploy --check`.
+type DeploySummary struct {
+	DBBranch string `json:"dbBranch"`
+	// BranchExists is false if deploying would create the branch, in which
+	// case the migration is computed against an empty schema
+	BranchExists bool                  `json:"branchExists"`
+	Pending      bool                  `json:"pending"`
+	Destructive  []string              `json:"destructive"`
+	Migration    *spec.BranchMigration `json:"migration,omitempty"`
+}
+
+// deployCheck reports whether deploying the schema file would change the
+// branch, without changing anything. It exits with 0 if the branch is up to
+// date and 2 if changes are pending.
+func deployCheck(c *cli.Context) error {
+	dir := c.String("dir")
+	schema, _, err := readSchemaFile(dir)
+	if err != nil {
+		return err
+	}
+
+	dbName, _, branch, err := getDBNameAndBranch(c)
+	if err != nil {
+		return err
+	}
+
+	workspaceID, err := getWorkspaceID(c)
+	if err != nil {
+		return err
+	}
+	apiKey, err := config.APIKey(c)
+	if err != nil {
+		return err
+	}
+	client, err := client.NewXataClient(apiKey, workspaceID)
+	if err != nil {
+		return err
+	}
+	existingBranches, err := getBranches(c, client, dbName)
+	if err != nil {
+		return err
+	}
+
+	summary := DeploySummary{
+		DBBranch:    fmt.Sprintf("%s:%s", dbName, branch),
+		Destructive: []string{},
+	}
+	for _, existingBranch := range existingBranches {
+		if existingBranch == branch {
+			summary.BranchExists = true
+		}
+	}
+
+	var migration spec.BranchMigration
+	if summary.BranchExists {
+		plan, _, err := planDeploy(c, dbName, branch, schema, false)
+		if err != nil {
+			return err
+		}
+		migration = plan.Migration
+	} else {
+		migration = PlanMigration(spec.Schema{}, schema)
+	}
+
+	summary.Pending = !summary.BranchExists || !isMigrationEmpty(migration)
+	if summary.Pending {
+		summary.Migration = &migration
+		summary.Destructive = destructiveChanges(migration)
+	}
+
+	if c.IsSet("markdown") {
+		err := filesystem.WriteFileAtomic(c.String("markdown"), []byte(deploySummaryMarkdown(summary)), 0644)
+		if err != nil {
+			return fmt.Errorf("writing summary file: %w", err)
+		}
+	}
+
+	if c.Bool("json") {
+		out, err := json.Marshal(summary)
+		if err != nil {
+			return err
+		}
+		if err := printJSON(c, out); err != nil {
+			return err
+		}
+	} else {
+		switch {
+		case !summary.BranchExists:
+			fmt.Printf("Branch [%s] doesn't exist yet, deploying creates it.\n", summary.DBBranch)
+		case summary.Pending:
+			previewMigration(c, migration)
+		default:
+			fmt.Println("Your schema is up to date.")
+		}
+	}
+
+	if summary.Pending {
+		return cli.Exit("", deployCheckPending)
+	}
+	return nil
+}
+
+// deploySummaryMarkdown renders the summary as Markdown, e.g. to post it as
+// a pull request comment.
+func deploySummaryMarkdown(summary DeploySummary) string {
+	var md strings.Builder
+	fmt.Fprintf(&md, "### Xata migration plan for `%s`\n\n", summary.DBBranch)
+
+	if !summary.Pending {
+		md.WriteString("The schema is up to date, there is nothing to deploy.\n")
+		return md.String()
+	}
+	if !summary.BranchExists {
+		md.WriteString("The branch doesn't exist yet, deploying creates it.\n\n")
+	}
+
+	migration := summary.Migration
+	if migration.NewTables != nil {
+		for _, table := range sortedTableNames(migration.NewTables.AdditionalProperties) {
+			fmt.Fprintf(&md, "- Create table `%s`\n", table)
+		}
+	}
+	if migration.RemovedTables != nil {
+		for _, table := range *migration.RemovedTables {
+			fmt.Fprintf(&md, "- Delete table `%s`\n", table)
+		}
+	}
+	if migration.RenamedTables != nil {
+		for _, rename := range *migration.RenamedTables {
+			fmt.Fprintf(&md, "- Rename table `%s` to `%s`\n", rename.OldName, rename.NewName)
+		}
+	}
+	if migration.TableMigrations != nil {
+		for _, table := range sortedTableMigrationNames(migration.TableMigrations.AdditionalProperties) {
+			tableMigration := migration.TableMigrations.AdditionalProperties[table]
+			fmt.Fprintf(&md, "- Table `%s`:\n", table)
+			if tableMigration.NewColumns != nil {
+				for _, column := range sortedColumnNames(tableMigration.NewColumns.AdditionalProperties) {
+					fmt.Fprintf(&md, "  - Add column `%s`\n", column)
+				}
+			}
+			if tableMigration.RemovedColumns != nil {
+				for _, column := range *tableMigration.RemovedColumns {
+					fmt.Fprintf(&md, "  - Delete column `%s`\n", column)
+				}
+			}
+			if tableMigration.ModifiedColumns != nil {
+				for _, column := range *tableMigration.ModifiedColumns {
+					if column.Old.Name != column.New.Name {
+						fmt.Fprintf(&md, "  - Rename column `%s` to `%s`\n", column.Old.Name, column.New.Name)
+					} else {
+						fmt.Fprintf(&md, "  - Modify column `%s`\n", column.Old.Name)
+					}
+				}
+			}
+		}
+	}
+
+	if len(summary.Destructive) > 0 {
+		md.WriteString("\n> **Warning:** this migration loses data, it:\n")
+		for _, change := range summary.Destructive {
+			fmt.Fprintf(&md, "> - %s\n", change)
+		}
+	}
+	return md.String()
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestDeploySummaryMarkdown(t *testing.T) {
+	from := spec.Schema{Tables: []spec.Table{
+		{Name: "users", Columns: []spec.Column{
+			{Name: "name", Type: spec.ColumnTypeString},
+			{Name: "age", Type: spec.ColumnTypeInt},
+		}},
+		{Name: "legacy", Columns: []spec.Column{}},
+	}}
+	to := spec.Schema{Tables: []spec.Table{
+		{Name: "users", Columns: []spec.Column{
+			{Name: "full_name", Type: spec.ColumnTypeString, RenamedFrom: "name"},
+			{Name: "email", Type: spec.ColumnTypeEmail},
+		}},
+		{Name: "posts", Columns: []spec.Column{}},
+	}}
+	migration := PlanMigration(from, to)
+	summary := DeploySummary{
+		DBBranch:     "mydb:main",
+		BranchExists: true,
+		Pending:      true,
+		Destructive:  destructiveChanges(migration),
+		Migration:    &migration,
+	}
+
+	require.Equal(t, "### Xata migration plan for `mydb:main`\n\n"+
+		"- Create table `posts`\n"+
+		"- Delete table `legacy`\n"+
+		"- Table `users`:\n"+
+		"  - Add column `email`\n"+
+		"  - Delete column `age`\n"+
+		"  - Rename column `name` to `full_name`\n"+
+		"\n> **Warning:** this migration loses data, it:\n"+
+		"> - drops column users.age\n"+
+		"> - drops table legacy\n", deploySummaryMarkdown(summary))
+
+	upToDate := DeploySummary{DBBranch: "mydb:main", BranchExists: true}
+	require.Equal(t, "### Xata migration plan for `mydb:main`\n\n"+
+		"The schema is up to date, there is nothing to deploy.\n", deploySummaryMarkdown(upToDate))
+}
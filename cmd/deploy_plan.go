@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/xataio/cli/client/spec"
+	"github.com/xataio/cli/filesystem"
+
+	"github.com/tidwall/pretty"
+	"github.com/urfave/cli/v2"
+)
+
+// SavedPlan is a migration plan written by `xata deploy plan`, to be applied
+// as is by `xata deploy apply`.
+type SavedPlan struct {
+	// DBBranch is the target of the plan, as db:branch
+	DBBranch string `json:"dbBranch"`
+	// LastMigrationID is the last migration of the branch when the plan was
+	// made. The plan is only applied if the branch hasn't moved since.
+	LastMigrationID string                   `json:"lastMigrationID"`
+	Plan            spec.BranchMigrationPlan `json:"plan"`
+}
+
+func (p SavedPlan) dbNameAndBranch() (string, string, error) {
+	parts := strings.SplitN(p.DBBranch, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid dbBranch %q in the plan, expected db:branch", p.DBBranch)
+	}
+	return parts[0], parts[1], nil
+}
+
+func GetDeploySubcommands() []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:   "plan",
+			Usage:  "Save the migration plan of the schema file to a file, to be applied later with `deploy apply`",
+			Action: DeployPlanCommand,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "out",
+					Aliases:  []string{"o"},
+					Usage:    "The file to write the plan to.",
+					Required: true,
+				},
+				&cli.BoolFlag{
+					Name:  "no-verify-plan",
+					Usage: "Don't check the server migration plan against a plan computed locally before saving it.",
+				},
+			},
+		},
+		{
+			Name:      "apply",
+			Usage:     "Apply a migration plan saved with `deploy plan`, if the branch hasn't changed since",
+			ArgsUsage: "<plan file>",
+			Action:    DeployApplyCommand,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "allow-destructive",
+					Usage: "Allow applying a plan that drops tables or columns, or changes column types.",
+				},
+			},
+		},
+		{
+			Name:      "revert",
+			Usage:     "Undo a past migration by deploying its inverse",
+			ArgsUsage: "<migration ID>",
+			Action:    DeployRevertCommand,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:    "force",
+					Aliases: []string{"f"},
+					Usage:   "Revert without asking for confirmation.",
+				},
+				&cli.BoolFlag{
+					Name:  "allow-destructive",
+					Usage: "Allow --force to drop the tables and columns the migration created.",
+				},
+			},
+		},
+	}
+}
+
+// DeployPlanCommand computes the migration plan of the schema file against
+// the branch, without creating the branch or changing anything.
+func DeployPlanCommand(c *cli.Context) error {
+	dir := c.String("dir")
+	schema, schemaFile, err := readSchemaFile(dir)
+	if err != nil {
+		return err
+	}
+
+	dbName, _, branch, err := getDBNameAndBranch(c)
+	if err != nil {
+		return err
+	}
+
+	plan, details, err := planDeploy(c, dbName, branch, schema, isInteractive(c))
+	if err != nil {
+		return err
+	}
+	setMigrationGitRevision(&plan.Migration, schemaFile)
+
+	saved := SavedPlan{
+		DBBranch:        fmt.Sprintf("%s:%s", dbName, branch),
+		LastMigrationID: details.LastMigrationID,
+		Plan:            *plan,
+	}
+	file, err := json.MarshalIndent(saved, "", " ")
+	if err != nil {
+		return err
+	}
+	out := c.String("out")
+	if err := filesystem.WriteFileAtomic(out, pretty.Pretty(file), 0644); err != nil {
+		return fmt.Errorf("writing plan file: %w", err)
+	}
+
+	if isMigrationEmpty(plan.Migration) {
+		fmt.Println("Your schema is up to date.")
+	} else {
+		previewMigration(c, plan.Migration)
+	}
+	fmt.Printf("Plan for [%s] saved to %s. Run `xata deploy apply %s` to apply it.\n", saved.DBBranch, out, out)
+	return nil
+}
+
+// DeployApplyCommand applies a saved plan without asking for confirmation,
+// refusing if the branch has been migrated since the plan was made.
+func DeployApplyCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("Expected exactly one argument: the plan file")
+	}
+	filename := c.Args().First()
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("reading plan file: %w", err)
+	}
+	var saved SavedPlan
+	if err := json.Unmarshal(bytes, &saved); err != nil {
+		return fmt.Errorf("unmarshaling plan file %s: %w", filename, err)
+	}
+	dbName, branch, err := saved.dbNameAndBranch()
+	if err != nil {
+		return err
+	}
+
+	if isMigrationEmpty(saved.Plan.Migration) {
+		fmt.Println("The plan is empty, nothing to apply.")
+		return nil
+	}
+
+	details, err := getBranchDetails(c, dbName, branch)
+	if err != nil {
+		return err
+	}
+	if details.LastMigrationID != saved.LastMigrationID {
+		return fmt.Errorf("Branch [%s] was migrated since the plan was made (last migration was [%s], now it is [%s]). Make a new plan with `xata deploy plan`.",
+			saved.DBBranch, saved.LastMigrationID, details.LastMigrationID)
+	}
+
+	settings, err := ReadSettings(c.String("dir"))
+	if err != nil {
+		return err
+	}
+	if err := checkDestructivePolicy(settings, branch, saved.Plan.Migration, true, c.Bool("allow-destructive")); err != nil {
+		return err
+	}
+
+	previewMigration(c, saved.Plan.Migration)
+	if err := executeMigrationPlan(c, dbName, branch, &saved.Plan); err != nil {
+		return err
+	}
+	fmt.Println("Done.")
+	return nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestSavedPlanRoundTrip(t *testing.T) {
+	migration := PlanMigration(spec.Schema{}, spec.Schema{Tables: []spec.Table{
+		{Name: "users", Columns: []spec.Column{{Name: "email", Type: spec.ColumnTypeEmail}}},
+	}})
+	saved := SavedPlan{
+		DBBranch:        "mydb:feature-x",
+		LastMigrationID: "mig_c7m19ilcefoebpqj12p0",
+		Plan:            spec.BranchMigrationPlan{Migration: migration},
+	}
+
+	bytes, err := json.Marshal(saved)
+	require.NoError(t, err)
+	var parsed SavedPlan
+	require.NoError(t, json.Unmarshal(bytes, &parsed))
+	require.Equal(t, saved.LastMigrationID, parsed.LastMigrationID)
+	require.Nil(t, compareMigrations("saved", saved.Plan.Migration, "parsed", parsed.Plan.Migration))
+
+	dbName, branch, err := parsed.dbNameAndBranch()
+	require.NoError(t, err)
+	require.Equal(t, "mydb", dbName)
+	require.Equal(t, "feature-x", branch)
+
+	_, _, err = SavedPlan{DBBranch: "mydb"}.dbNameAndBranch()
+	require.Error(t, err)
+}
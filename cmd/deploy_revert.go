@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/urfave/cli/v2"
+)
+
+// invertMigration returns the migration that undoes the given one. The
+// schema before the migration provides the definitions of the tables and
+// columns it removed.
+func invertMigration(migration spec.BranchMigration, before spec.Schema) spec.BranchMigration {
+	beforeTables := tablesByName(before)
+	inverse := spec.BranchMigration{NewTableOrder: []string{}}
+	for _, table := range before.Tables {
+		inverse.NewTableOrder = append(inverse.NewTableOrder, table.Name)
+	}
+
+	if migration.NewTables != nil {
+		for _, name := range sortedTableNames(migration.NewTables.AdditionalProperties) {
+			if inverse.RemovedTables == nil {
+				inverse.RemovedTables = &[]string{}
+			}
+			*inverse.RemovedTables = append(*inverse.RemovedTables, name)
+		}
+	}
+	for _, name := range stringList(migration.RemovedTables) {
+		if inverse.NewTables == nil {
+			inverse.NewTables = &spec.BranchMigration_NewTables{}
+		}
+		inverse.NewTables.Set(name, beforeTables[name])
+	}
+
+	// table migrations are keyed by the name the table has after the renames
+	oldTableNames := renamedTableNames(migration)
+	if migration.RenamedTables != nil {
+		renames := []spec.TableRename{}
+		for _, rename := range *migration.RenamedTables {
+			renames = append(renames, spec.TableRename{OldName: rename.NewName, NewName: rename.OldName})
+		}
+		inverse.RenamedTables = &renames
+	}
+
+	if migration.TableMigrations != nil {
+		for _, name := range sortedTableMigrationNames(migration.TableMigrations.AdditionalProperties) {
+			tableMigration := migration.TableMigrations.AdditionalProperties[name]
+			oldName := name
+			if renamedFrom, ok := oldTableNames[name]; ok {
+				oldName = renamedFrom
+			}
+			beforeTable := beforeTables[oldName]
+
+			inverseTable := spec.TableMigration{NewColumnOrder: columnNames(beforeTable.Columns)}
+			if tableMigration.NewColumns != nil {
+				removed := sortedColumnNames(tableMigration.NewColumns.AdditionalProperties)
+				inverseTable.RemovedColumns = &removed
+			}
+			for _, column := range stringList(tableMigration.RemovedColumns) {
+				if inverseTable.NewColumns == nil {
+					inverseTable.NewColumns = &spec.TableMigration_NewColumns{}
+				}
+				definition, _ := findColumn(beforeTable.Columns, column)
+				inverseTable.NewColumns.Set(column, definition)
+			}
+			if tableMigration.ModifiedColumns != nil {
+				modified := []spec.ColumnMigration{}
+				for _, column := range *tableMigration.ModifiedColumns {
+					modified = append(modified, spec.ColumnMigration{Old: column.New, New: column.Old})
+				}
+				inverseTable.ModifiedColumns = &modified
+			}
+
+			if inverse.TableMigrations == nil {
+				inverse.TableMigrations = &spec.BranchMigration_TableMigrations{}
+			}
+			inverse.TableMigrations.Set(oldName, inverseTable)
+		}
+	}
+	return inverse
+}
+
+// checkRevertApplies makes sure the tables and columns the inverse migration
+// works on are still as the reverted migration left them, which isn't the
+// case if later migrations changed them.
+func checkRevertApplies(current spec.Schema, migration spec.BranchMigration) error {
+	currentTables := tablesByName(current)
+	problems := []string{}
+
+	if migration.NewTables != nil {
+		for _, name := range sortedTableNames(migration.NewTables.AdditionalProperties) {
+			if _, ok := currentTables[name]; !ok {
+				problems = append(problems, fmt.Sprintf("table %s doesn't exist anymore", name))
+			}
+		}
+	}
+	for _, name := range stringList(migration.RemovedTables) {
+		if _, ok := currentTables[name]; ok {
+			problems = append(problems, fmt.Sprintf("table %s exists again", name))
+		}
+	}
+	if migration.RenamedTables != nil {
+		for _, rename := range *migration.RenamedTables {
+			if _, ok := currentTables[rename.NewName]; !ok {
+				problems = append(problems, fmt.Sprintf("table %s doesn't exist anymore", rename.NewName))
+			}
+			if _, ok := currentTables[rename.OldName]; ok {
+				problems = append(problems, fmt.Sprintf("table %s exists again", rename.OldName))
+			}
+		}
+	}
+	if migration.TableMigrations != nil {
+		for _, name := range sortedTableMigrationNames(migration.TableMigrations.AdditionalProperties) {
+			table, ok := currentTables[name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("table %s doesn't exist anymore", name))
+				continue
+			}
+			tableMigration := migration.TableMigrations.AdditionalProperties[name]
+			if tableMigration.NewColumns != nil {
+				for _, column := range sortedColumnNames(tableMigration.NewColumns.AdditionalProperties) {
+					if _, ok := findColumn(table.Columns, column); !ok {
+						problems = append(problems, fmt.Sprintf("column %s.%s doesn't exist anymore", name, column))
+					}
+				}
+			}
+			for _, column := range stringList(tableMigration.RemovedColumns) {
+				if _, ok := findColumn(table.Columns, column); ok {
+					problems = append(problems, fmt.Sprintf("column %s.%s exists again", name, column))
+				}
+			}
+			if tableMigration.ModifiedColumns != nil {
+				for _, column := range *tableMigration.ModifiedColumns {
+					currentColumn, ok := findColumn(table.Columns, column.New.Name)
+					if !ok {
+						problems = append(problems, fmt.Sprintf("column %s.%s doesn't exist anymore", name, column.New.Name))
+					} else if !columnsEqual(currentColumn, column.New) {
+						problems = append(problems, fmt.Sprintf("column %s.%s was changed since", name, column.New.Name))
+					}
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("The migration can't be reverted because later migrations changed the same tables:\n  %s",
+			strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// revertTarget returns the schema to deploy to undo a migration, with
+// renamedFrom hints so that the renames are undone without losing data.
+func revertTarget(current spec.Schema, inverse spec.BranchMigration) spec.Schema {
+	target := applyMigration(current, inverse)
+	tables := make([]spec.Table, 0, len(target.Tables))
+	for _, table := range target.Tables {
+		for _, rename := range tableRenameList(inverse.RenamedTables) {
+			if rename.NewName == table.Name {
+				table.RenamedFrom = rename.OldName
+			}
+		}
+		if inverse.TableMigrations != nil {
+			if tableMigration, ok := inverse.TableMigrations.Get(table.Name); ok && tableMigration.ModifiedColumns != nil {
+				columns := make([]spec.Column, 0, len(table.Columns))
+				for _, column := range table.Columns {
+					for _, modified := range *tableMigration.ModifiedColumns {
+						if modified.New.Name == column.Name && modified.Old.Name != column.Name {
+							column.RenamedFrom = modified.Old.Name
+						}
+					}
+					columns = append(columns, column)
+				}
+				table.Columns = columns
+			}
+		}
+		tables = append(tables, table)
+	}
+	target.Tables = tables
+	return target
+}
+
+func tableRenameList(renames *[]spec.TableRename) []spec.TableRename {
+	if renames == nil {
+		return []spec.TableRename{}
+	}
+	return *renames
+}
+
+// DeployRevertCommand undoes a past migration by deploying the inverse plan.
+func DeployRevertCommand(c *cli.Context) error {
+	force := c.Bool("force")
+	if interactive, reason := isInteractiveWithReason(c); !force && !interactive {
+		return fmt.Errorf("The revert command is interactive but %s. Use --force to revert without asking for confirmation.", reason)
+	}
+	if c.NArg() != 1 {
+		return fmt.Errorf("Expected exactly one argument: the ID of the migration to revert")
+	}
+	migrationID := c.Args().First()
+
+	dbName, _, branch, err := getDBNameAndBranch(c)
+	if err != nil {
+		return err
+	}
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+
+	migrations, err := getMigrationHistory(c.Context, client, dbName, branch, "", true)
+	if err != nil {
+		return err
+	}
+	i := findMigrationIndex(migrations, migrationID, "")
+	if i < 0 {
+		return fmt.Errorf("Migration [%s] isn't in the history of branch [%s:%s]", migrationID, dbName, branch)
+	}
+	migration := migrations[i]
+	if migration.Status == "failed" {
+		return fmt.Errorf("Migration [%s] failed, there is nothing to revert", migrationID)
+	}
+
+	details, err := getBranchDetails(c, dbName, branch)
+	if err != nil {
+		return err
+	}
+	if err := checkRevertApplies(details.Schema, migration); err != nil {
+		return err
+	}
+	inverse := invertMigration(migration, replayMigrations(migrations[i+1:]))
+	target := revertTarget(details.Schema, inverse)
+
+	plan, _, err := planDeploy(c, dbName, branch, target, false)
+	if err != nil {
+		return err
+	}
+	if isMigrationEmpty(plan.Migration) {
+		fmt.Println("The migration has no effect on the current schema, there is nothing to revert.")
+		return nil
+	}
+
+	settings, err := ReadSettings(c.String("dir"))
+	if err != nil {
+		return err
+	}
+	if err := checkDestructivePolicy(settings, branch, plan.Migration, force, c.Bool("allow-destructive")); err != nil {
+		return err
+	}
+
+	if lost := destructiveChanges(migration); len(lost) > 0 {
+		red := color.New(color.FgRed)
+		if c.Bool("nocolor") {
+			red.DisableColor()
+		}
+		red.Printf("Warning: the data removed by migration [%s] can't be recovered, the restored tables and columns are empty. The migration:\n  %s\n\n",
+			migrationID, strings.Join(lost, "\n  "))
+	}
+	previewMigration(c, plan.Migration)
+
+	yes := force
+	if !force {
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("Revert migration [%s] with the above migration?", migrationID),
+			Default: false,
+		}
+		survey.AskOne(prompt, &yes)
+	}
+	if !yes {
+		return nil
+	}
+
+	title := fmt.Sprintf("Revert %s", migrationID)
+	plan.Migration.Title = &title
+	if err := executeMigrationPlan(c, dbName, branch, plan); err != nil {
+		return err
+	}
+	fmt.Println("Done.")
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestInvertMigration(t *testing.T) {
+	before := spec.Schema{FormatVersion: "1.0", Tables: []spec.Table{
+		{Name: "users", Columns: []spec.Column{
+			{Name: "name", Type: spec.ColumnTypeString},
+			{Name: "age", Type: spec.ColumnTypeInt, Required: true},
+			{Name: "bio", Type: spec.ColumnTypeText},
+		}},
+		{Name: "legacy", Columns: []spec.Column{{Name: "data", Type: spec.ColumnTypeText}}},
+	}}
+	after := spec.Schema{FormatVersion: "1.0", Tables: []spec.Table{
+		{Name: "people", RenamedFrom: "users", Columns: []spec.Column{
+			{Name: "full_name", RenamedFrom: "name", Type: spec.ColumnTypeString},
+			{Name: "age", Type: spec.ColumnTypeString},
+			{Name: "email", Type: spec.ColumnTypeEmail},
+		}},
+		{Name: "posts", Columns: []spec.Column{{Name: "title", Type: spec.ColumnTypeString}}},
+	}}
+	migration := PlanMigration(before, after)
+	current := applyMigration(before, migration)
+	require.True(t, schemasEqual(stripRenameHints(after), current))
+	require.NoError(t, checkRevertApplies(current, migration))
+
+	inverse := invertMigration(migration, before)
+	require.True(t, schemasEqual(before, applyMigration(current, inverse)))
+	require.Equal(t, &[]spec.TableRename{{OldName: "people", NewName: "users"}}, inverse.RenamedTables)
+	require.Equal(t, &[]string{"posts"}, inverse.RemovedTables)
+
+	// the hints make a plan computed by name undo the renames too
+	target := revertTarget(current, inverse)
+	plan := PlanMigration(current, target)
+	require.Equal(t, inverse.RenamedTables, plan.RenamedTables)
+	users, ok := plan.TableMigrations.Get("users")
+	require.True(t, ok)
+	require.Equal(t, &[]string{"email"}, users.RemovedColumns)
+	require.Equal(t, []string{"bio"}, sortedColumnNames(users.NewColumns.AdditionalProperties))
+	require.Len(t, *users.ModifiedColumns, 2)
+
+	// a later migration removed the email column
+	changed := applyMigration(current, PlanMigration(current, spec.Schema{Tables: []spec.Table{
+		{Name: "people", Columns: current.Tables[0].Columns[:2]},
+		current.Tables[1],
+	}}))
+	require.EqualError(t, checkRevertApplies(changed, migration),
+		"The migration can't be reverted because later migrations changed the same tables:\n  column people.email doesn't exist anymore")
+}
@@ -0,0 +1,327 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	xataclient "github.com/xataio/cli/client"
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/urfave/cli/v2"
+)
+
+// recordWriter writes exported records in a file format.
+type recordWriter interface {
+	Write(record map[string]interface{}) error
+	Close() error
+}
+
+// exportValues returns the id and columns of a record, with links replaced
+// by the ID of the linked record, unless columns of the linked records were
+// selected.
+func exportValues(table spec.Table, record spec.Record, selected []string) map[string]interface{} {
+	expanded := map[string]bool{}
+	for _, path := range selected {
+		parts := strings.Split(path, ".")
+		for i := 1; i < len(parts); i++ {
+			expanded[strings.Join(parts[:i], ".")] = true
+		}
+	}
+	values := linksToIDs("", table.Columns, record.AdditionalProperties, expanded)
+	values["id"] = string(record.Id)
+	return values
+}
+
+func linksToIDs(prefix string, columns []spec.Column, values map[string]interface{}, expanded map[string]bool) map[string]interface{} {
+	out := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		column, ok := findColumn(columns, name)
+		nested, isObject := value.(map[string]interface{})
+		switch {
+		case ok && isObject && column.Type == spec.ColumnTypeLink && !expanded[prefix+name]:
+			value = nested["id"]
+		case ok && isObject && column.Type == spec.ColumnTypeObject:
+			value = linksToIDs(prefix+name+".", column.Columns, nested, expanded)
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// exportColumn is a CSV column: a column of the table, or of an object
+// column, by dotted path.
+type exportColumn struct {
+	path   string
+	column spec.Column
+}
+
+// exportColumns lists the CSV columns of a table, with objects flattened to
+// their columns. If selected isn't empty, only these columns, or the columns
+// of these objects, are exported.
+func exportColumns(table spec.Table, selected []string) []exportColumn {
+	if len(selected) == 0 {
+		return flattenColumns("", table.Columns)
+	}
+	columns := []exportColumn{}
+	for _, path := range selected {
+		column, ok := columnAtPath(table.Columns, strings.Split(path, "."))
+		if !ok {
+			// e.g. the columns of linked records
+			column = spec.Column{Name: path, Type: spec.ColumnTypeString}
+		}
+		if column.Type == spec.ColumnTypeObject {
+			columns = append(columns, flattenColumns(path+".", column.Columns)...)
+			continue
+		}
+		columns = append(columns, exportColumn{path: path, column: column})
+	}
+	return columns
+}
+
+func flattenColumns(prefix string, columns []spec.Column) []exportColumn {
+	flat := []exportColumn{}
+	for _, column := range columns {
+		if column.Type == spec.ColumnTypeObject {
+			flat = append(flat, flattenColumns(prefix+column.Name+".", column.Columns)...)
+			continue
+		}
+		flat = append(flat, exportColumn{path: prefix + column.Name, column: column})
+	}
+	return flat
+}
+
+// csvCell formats a value for CSV. The values of multiple columns are joined
+// with the separator.
+func csvCell(value interface{}, separator string) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, csvCell(item, separator))
+		}
+		return strings.Join(items, separator)
+	case map[string]interface{}:
+		if id, ok := v["id"].(string); ok {
+			return id
+		}
+	}
+	return recordCellValue(value)
+}
+
+type csvRecordWriter struct {
+	writer    *csv.Writer
+	columns   []exportColumn
+	separator string
+	header    bool
+}
+
+func newCSVRecordWriter(w io.Writer, columns []exportColumn, delimiter rune, separator string) *csvRecordWriter {
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+	return &csvRecordWriter{writer: writer, columns: columns, separator: separator}
+}
+
+func (w *csvRecordWriter) writeHeader() error {
+	w.header = true
+	header := []string{"id"}
+	for _, column := range w.columns {
+		header = append(header, column.path)
+	}
+	return w.writer.Write(header)
+}
+
+func (w *csvRecordWriter) Write(record map[string]interface{}) error {
+	if !w.header {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+	row := []string{csvCell(record["id"], w.separator)}
+	for _, column := range w.columns {
+		row = append(row, csvCell(recordValue(record, column.path), w.separator))
+	}
+	return w.writer.Write(row)
+}
+
+func (w *csvRecordWriter) Close() error {
+	if !w.header {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type ndjsonRecordWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonRecordWriter) Write(record map[string]interface{}) error {
+	return w.encoder.Encode(record)
+}
+
+func (w *ndjsonRecordWriter) Close() error {
+	return nil
+}
+
+// jsonRecordWriter writes a JSON array, one record at a time.
+type jsonRecordWriter struct {
+	writer io.Writer
+	count  int
+}
+
+func (w *jsonRecordWriter) Write(record map[string]interface{}) error {
+	out, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	separator := ",\n  "
+	if w.count == 0 {
+		separator = "[\n  "
+	}
+	w.count++
+	if _, err := io.WriteString(w.writer, separator); err != nil {
+		return err
+	}
+	_, err = w.writer.Write(out)
+	return err
+}
+
+func (w *jsonRecordWriter) Close() error {
+	end := "\n]\n"
+	if w.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(w.writer, end)
+	return err
+}
+
+func newRecordWriter(w io.Writer, format string, columns []exportColumn, delimiter rune, separator string) (recordWriter, error) {
+	switch format {
+	case "csv":
+		return newCSVRecordWriter(w, columns, delimiter, separator), nil
+	case "ndjson":
+		return &ndjsonRecordWriter{encoder: json.NewEncoder(w)}, nil
+	case "json":
+		return &jsonRecordWriter{writer: w}, nil
+	}
+	return nil, fmt.Errorf("Invalid --format %s: expected csv, ndjson or json", format)
+}
+
+// exportRecords writes all the records of the iterator and returns how many
+// were written.
+func exportRecords(c *cli.Context, it *xataclient.RecordIterator, table spec.Table, selected []string, writer recordWriter) (int, error) {
+	count := 0
+	for it.Next(c.Context) {
+		if err := writer.Write(exportValues(table, it.Record(), selected)); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := iteratorError(it.Err()); err != nil {
+		return count, err
+	}
+	return count, writer.Close()
+}
+
+// ExportCommand exports the records of a table to CSV, NDJSON or JSON.
+func ExportCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("Expected exactly one argument: the table name")
+	}
+	table := c.Args().First()
+	format := c.String("format")
+	out := c.String("out")
+	if format == "" {
+		format = "ndjson"
+		if out != "" {
+			if detected, err := importFormat(out, ""); err == nil {
+				format = detected
+			} else if strings.HasSuffix(strings.ToLower(out), ".json") {
+				format = "json"
+			}
+		}
+	}
+	delimiter := []rune(c.String("delimiter"))
+	if len(delimiter) != 1 {
+		return fmt.Errorf("Invalid --delimiter %q: must be a single character", c.String("delimiter"))
+	}
+	// check the format before --out gets truncated
+	if _, err := newRecordWriter(nil, format, nil, delimiter[0], ""); err != nil {
+		return err
+	}
+	query, err := buildQuery(c)
+	if err != nil {
+		return err
+	}
+
+	dbBranch, dbName, branch, err := getDBBranchParam(c)
+	if err != nil {
+		return err
+	}
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+	details, err := getBranchDetails(c, dbName, branch)
+	if err != nil {
+		return err
+	}
+	schemaTable, ok := tablesByName(details.Schema)[table]
+	if !ok {
+		return fmt.Errorf("Table [%s] doesn't exist in branch [%s:%s]", table, dbName, branch)
+	}
+
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if out != "" {
+		file, err = os.Create(out)
+		if err != nil {
+			return err
+		}
+		w = file
+	}
+	buffered := bufio.NewWriter(w)
+	selected := c.StringSlice("columns")
+	writer, err := newRecordWriter(buffered, format, exportColumns(schemaTable, selected),
+		delimiter[0], c.String("multiple-separator"))
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		return err
+	}
+
+	it := xataclient.NewRecordIterator(client, dbBranch, spec.TableNameParam(table), query, 0)
+	count, err := exportRecords(c, it, schemaTable, selected, writer)
+	if flushErr := buffered.Flush(); err == nil {
+		err = flushErr
+	}
+	if file != nil {
+		if closeErr := file.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("writing %s: %w", out, closeErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if out != "" {
+		fmt.Printf("Exported %d records from table [%s] of [%s] to %s\n", count, table, dbBranch, out)
+	}
+	return nil
+}
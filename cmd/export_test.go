@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func exportFixture() spec.Record {
+	return spec.Record{
+		Id: "rec_1",
+		AdditionalProperties: map[string]interface{}{
+			"name":    "Alice, Jr",
+			"age":     float64(42),
+			"score":   1.5,
+			"active":  true,
+			"tags":    []interface{}{"a", "b"},
+			"team":    map[string]interface{}{"id": "rec_t", "name": "core"},
+			"address": map[string]interface{}{"street": "Main St", "zipcode": float64(12345)},
+		},
+	}
+}
+
+func TestExportValues(t *testing.T) {
+	values := exportValues(usersTable, exportFixture(), nil)
+	require.Equal(t, "rec_1", values["id"])
+	require.Equal(t, "rec_t", values["team"])
+
+	values = exportValues(usersTable, exportFixture(), []string{"name", "team.name"})
+	require.Equal(t, map[string]interface{}{"id": "rec_t", "name": "core"}, values["team"])
+}
+
+func TestExportColumns(t *testing.T) {
+	paths := func(columns []exportColumn) []string {
+		all := []string{}
+		for _, column := range columns {
+			all = append(all, column.path)
+		}
+		return all
+	}
+	require.Equal(t, []string{"name", "age", "score", "active", "tags", "team", "address.street", "address.zipcode"},
+		paths(exportColumns(usersTable, nil)))
+	require.Equal(t, []string{"address.street", "address.zipcode", "team.name"},
+		paths(exportColumns(usersTable, []string{"address", "team.name"})))
+}
+
+func TestExportCSV(t *testing.T) {
+	var out bytes.Buffer
+	writer := newCSVRecordWriter(&out, exportColumns(usersTable, nil), ',', ";")
+	require.NoError(t, writer.Write(exportValues(usersTable, exportFixture(), nil)))
+	require.NoError(t, writer.Write(map[string]interface{}{"id": "rec_2"}))
+	require.NoError(t, writer.Close())
+	require.Equal(t, "id,name,age,score,active,tags,team,address.street,address.zipcode\n"+
+		"rec_1,\"Alice, Jr\",42,1.5,true,a;b,rec_t,Main St,12345\n"+
+		"rec_2,,,,,,,,\n", out.String())
+
+	// the export can be imported back
+	rows, err := newCSVRowReader(strings.NewReader(out.String()), usersTable, ',', ";")
+	require.NoError(t, err)
+	all := readAllRows(t, rows)
+	require.Len(t, all, 2)
+	require.NoError(t, all[0].err)
+	require.Equal(t, []string{"a", "b"}, all[0].record["tags"])
+
+	out.Reset()
+	writer = newCSVRecordWriter(&out, exportColumns(usersTable, []string{"name"}), ';', ";")
+	require.NoError(t, writer.Close())
+	require.Equal(t, "id;name\n", out.String())
+}
+
+func TestExportJSON(t *testing.T) {
+	var out bytes.Buffer
+	writer, err := newRecordWriter(&out, "json", nil, ',', ";")
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.Equal(t, "[]\n", out.String())
+
+	out.Reset()
+	writer, err = newRecordWriter(&out, "json", nil, ',', ";")
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(map[string]interface{}{"id": "rec_1"}))
+	require.NoError(t, writer.Write(map[string]interface{}{"id": "rec_2"}))
+	require.NoError(t, writer.Close())
+	require.JSONEq(t, `[{"id":"rec_1"},{"id":"rec_2"}]`, out.String())
+
+	out.Reset()
+	writer, err = newRecordWriter(&out, "ndjson", nil, ',', ";")
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(map[string]interface{}{"id": "rec_1"}))
+	require.NoError(t, writer.Close())
+	require.Equal(t, "{\"id\":\"rec_1\"}\n", out.String())
+
+	_, err = newRecordWriter(&out, "xml", nil, ',', ";")
+	require.Error(t, err)
+}
+
+func TestNewRecordWriterFormats(t *testing.T) {
+	for _, format := range []string{"csv", "ndjson", "json"} {
+		_, err := newRecordWriter(nil, format, nil, ',', "")
+		require.NoError(t, err, format)
+	}
+	_, err := newRecordWriter(nil, "xml", nil, ',', "")
+	require.EqualError(t, err, "Invalid --format xml: expected csv, ndjson or json")
+}
@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/xataio/cli/client"
@@ -15,6 +17,11 @@ import (
 )
 
 func HistoryCommand(c *cli.Context) error {
+	filter, err := newHistoryFilter(c)
+	if err != nil {
+		return err
+	}
+
 	dbName, _, branch, err := getDBNameAndBranch(c)
 	if err != nil {
 		return err
@@ -34,8 +41,20 @@ func HistoryCommand(c *cli.Context) error {
 		return err
 	}
 
-	fmt.Printf("Migrations log of [%s]:\n\n", dbName)
-	err = printHistory(c.Context, client, dbName, branch, "", c.Bool("follow"), c.Bool("nocolor"))
+	format := historyFormatDefault
+	switch {
+	case c.Bool("json"):
+		format = historyFormatJSON
+	case c.Bool("oneline"):
+		format = historyFormatOneline
+	case c.Bool("details"):
+		format = historyFormatDetails
+	}
+
+	if format == historyFormatDefault || format == historyFormatDetails {
+		fmt.Printf("Migrations log of [%s]:\n\n", dbName)
+	}
+	err = printHistory(c.Context, client, dbName, branch, "", c.Bool("follow"), c.Bool("nocolor"), filter, format)
 	if err != nil {
 		return err
 	}
@@ -44,6 +63,17 @@ func HistoryCommand(c *cli.Context) error {
 }
 
 func PrintMigration(migration spec.BranchMigration, withNoColor bool) {
+	printMigration(migration, withNoColor, false)
+}
+
+// PrintMigrationDetails prints the migration like PrintMigration, together
+// with the definition of the new tables and columns, and what changes in
+// each modified column.
+func PrintMigrationDetails(migration spec.BranchMigration, withNoColor bool) {
+	printMigration(migration, withNoColor, true)
+}
+
+func printMigration(migration spec.BranchMigration, withNoColor bool, detailed bool) {
 	blue := color.New(color.BgBlue).Add(color.FgWhite)
 	red := color.New(color.BgRed).Add(color.FgWhite)
 	yellow := color.New(color.BgYellow).Add(color.FgBlack)
@@ -76,9 +106,12 @@ func PrintMigration(migration spec.BranchMigration, withNoColor bool) {
 	}
 
 	if migration.NewTables != nil {
-		for tableName := range migration.NewTables.AdditionalProperties {
+		for _, tableName := range sortedTableNames(migration.NewTables.AdditionalProperties) {
 			blue.Printf(" CREATE table ")
 			fmt.Printf(" %s\n", tableName)
+			if detailed {
+				printColumnDefinitions(indent+indent, migration.NewTables.AdditionalProperties[tableName].Columns)
+			}
 		}
 	}
 	if migration.RemovedTables != nil {
@@ -95,13 +128,19 @@ func PrintMigration(migration spec.BranchMigration, withNoColor bool) {
 	}
 
 	if migration.TableMigrations != nil {
-		for tableName, tableMigration := range migration.TableMigrations.AdditionalProperties {
+		for _, tableName := range sortedTableMigrationNames(migration.TableMigrations.AdditionalProperties) {
+			tableMigration := migration.TableMigrations.AdditionalProperties[tableName]
 			fmt.Printf("Table [%s]:\n", tableName)
 			if tableMigration.NewColumns != nil {
-				for columnName := range tableMigration.NewColumns.AdditionalProperties {
+				for _, columnName := range sortedColumnNames(tableMigration.NewColumns.AdditionalProperties) {
 					fmt.Print(indent)
 					blue.Printf(" ADD column ")
 					fmt.Printf(" %s\n", columnName)
+					if detailed {
+						column := tableMigration.NewColumns.AdditionalProperties[columnName]
+						column.Name = columnName
+						printColumnDefinitions(indent+indent+indent, []spec.Column{column})
+					}
 				}
 			}
 			if tableMigration.RemovedColumns != nil {
@@ -115,13 +154,51 @@ func PrintMigration(migration spec.BranchMigration, withNoColor bool) {
 				for _, column := range *tableMigration.ModifiedColumns {
 					fmt.Print(indent)
 					yellow.Printf(" MODIFY column ")
-					fmt.Printf(" %s\n", column.Old.Name)
+					if !detailed {
+						fmt.Printf(" %s\n", column.Old.Name)
+						continue
+					}
+					if column.Old.Name != column.New.Name {
+						fmt.Printf(" %s TO %s\n", column.Old.Name, column.New.Name)
+					} else {
+						fmt.Printf(" %s\n", column.Old.Name)
+					}
+					for _, change := range columnChanges("", column.Old, column.New) {
+						fmt.Printf("%s%s\n", indent+indent+indent, change)
+					}
 				}
 			}
 		}
 	}
 }
 
+func sortedTableNames(tables map[string]spec.Table) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedTableMigrationNames(tables map[string]spec.TableMigration) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedColumnNames(columns map[string]spec.Column) []string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func checkHistoryResponse(history *spec.GetBranchMigrationHistoryResponse) error {
 	if history.JSON401 != nil {
 		return ErrorUnauthorized{message: history.JSON401.Message}
@@ -142,48 +219,109 @@ func checkHistoryResponse(history *spec.GetBranchMigrationHistoryResponse) error
 	return nil
 }
 
-func printHistory(ctx context.Context, client *spec.ClientWithResponses, dbName, branchName,
-	startFromID string, follow bool, withNoColor bool) error {
-	startFrom := startFromID
-	var originBase *spec.StartedFromMetadata
-	// TODO: this needs to be updated to use StartedFromBranch
+// walkMigrationHistory calls visit with the migrations of a branch, newest
+// first, starting from startFromID (or the latest migration if empty). When
+// the branch was created by copying another one, visit is then called with
+// the origin, and with follow the walk continues into that branch. The walk
+// stops early if visit returns false.
+func walkMigrationHistory(ctx context.Context, client *spec.ClientWithResponses, dbName, branchName,
+	startFromID string, follow bool,
+	visit func(migration *spec.BranchMigration, origin *spec.StartedFromMetadata) bool) error {
 	for {
-		req := spec.GetBranchMigrationHistoryJSONRequestBody{
-			StartFrom: &startFrom,
-		}
-		dbbranch := spec.DBBranchNameParam(fmt.Sprintf("%s:%s", dbName, branchName))
-		history, err := client.GetBranchMigrationHistoryWithResponse(ctx, dbbranch, req)
-		if err != nil {
-			return fmt.Errorf("Error getting migrations: %w", err)
-		}
-		err = checkHistoryResponse(history)
-		if err != nil {
-			return err
-		}
+		startFrom := startFromID
+		var originBase *spec.StartedFromMetadata
+		// TODO: this needs to be updated to use StartedFromBranch
+		for {
+			req := spec.GetBranchMigrationHistoryJSONRequestBody{
+				StartFrom: &startFrom,
+			}
+			dbbranch := spec.DBBranchNameParam(fmt.Sprintf("%s:%s", dbName, branchName))
+			history, err := client.GetBranchMigrationHistoryWithResponse(ctx, dbbranch, req)
+			if err != nil {
+				return fmt.Errorf("Error getting migrations: %w", err)
+			}
+			err = checkHistoryResponse(history)
+			if err != nil {
+				return err
+			}
 
-		migrations := *history.JSON200.Migrations
-		originBase = history.JSON200.StartedFrom
-		for _, migration := range migrations {
-			PrintMigration(migration, withNoColor)
-			fmt.Println()
+			migrations := []spec.BranchMigration{}
+			if history.JSON200.Migrations != nil {
+				migrations = *history.JSON200.Migrations
+			}
+			originBase = history.JSON200.StartedFrom
+			for i := range migrations {
+				if !visit(&migrations[i], nil) {
+					return nil
+				}
+			}
+			if len(migrations) == 0 || migrations[len(migrations)-1].ParentID == nil {
+				break
+			}
+			startFrom = *migrations[len(migrations)-1].ParentID
 		}
-		if len(migrations) == 0 {
-			break
+
+		if originBase == nil {
+			return nil
 		}
-		if migrations[len(migrations)-1].ParentID == nil {
-			break
+		if !visit(nil, originBase) || !follow {
+			return nil
 		}
-		startFrom = *migrations[len(migrations)-1].ParentID
+		branchName = string(originBase.BranchName)
+		startFromID = originBase.MigrationID
 	}
+}
 
-	if originBase != nil {
-		yellowFG := color.New(color.FgYellow)
-		yellowFG.Printf("➜ created by copying schema from branch [%s @ %s]\n", originBase.BranchName, originBase.MigrationID)
+func printHistory(ctx context.Context, client *spec.ClientWithResponses, dbName, branchName,
+	startFromID string, follow bool, withNoColor bool, filter historyFilter, format string) error {
+	yellowFG := color.New(color.FgYellow)
+	if withNoColor {
+		yellowFG.DisableColor()
 	}
 
-	if follow && originBase != nil {
-		fmt.Println()
-		printHistory(ctx, client, dbName, string(originBase.BranchName), originBase.MigrationID, follow, withNoColor)
+	var printErr error
+	printed := 0
+	err := walkMigrationHistory(ctx, client, dbName, branchName, startFromID, follow,
+		func(migration *spec.BranchMigration, origin *spec.StartedFromMetadata) bool {
+			if origin != nil {
+				if format != historyFormatJSON {
+					yellowFG.Printf("➜ created by copying schema from branch [%s @ %s]\n", origin.BranchName, origin.MigrationID)
+					if follow && format != historyFormatOneline {
+						fmt.Println()
+					}
+				}
+				return true
+			}
+			if filter.isBefore(*migration) {
+				// the history is sorted by date, nothing older can match
+				return false
+			}
+			if !filter.matches(*migration) {
+				return true
+			}
+
+			switch format {
+			case historyFormatJSON:
+				out, err := json.Marshal(migration)
+				if err != nil {
+					printErr = err
+					return false
+				}
+				fmt.Println(string(out))
+			case historyFormatOneline:
+				fmt.Println(migrationOneline(*migration))
+			case historyFormatDetails:
+				PrintMigrationDetails(*migration, withNoColor)
+				fmt.Println()
+			default:
+				PrintMigration(*migration, withNoColor)
+				fmt.Println()
+			}
+			printed++
+			return filter.limit == 0 || printed < filter.limit
+		})
+	if err != nil {
+		return err
 	}
-	return nil
+	return printErr
 }
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	historyFormatDefault = "default"
+	historyFormatJSON    = "json"
+	historyFormatOneline = "oneline"
+	historyFormatDetails = "details"
+)
+
+// historyFilter selects the migrations printed by `xata log`. Zero values
+// match everything.
+type historyFilter struct {
+	since  time.Time
+	until  time.Time
+	table  string
+	gitSHA string
+	limit  int
+}
+
+func newHistoryFilter(c *cli.Context) (historyFilter, error) {
+	filter := historyFilter{
+		table:  c.String("table"),
+		gitSHA: c.String("git-sha"),
+		limit:  c.Int("limit"),
+	}
+	if filter.limit < 0 {
+		return filter, fmt.Errorf("--limit must be positive")
+	}
+	var err error
+	if c.IsSet("since") {
+		filter.since, err = parseHistoryTime(c.String("since"))
+		if err != nil {
+			return filter, fmt.Errorf("Invalid --since: %w", err)
+		}
+	}
+	if c.IsSet("until") {
+		filter.until, err = parseHistoryUntil(c.String("until"))
+		if err != nil {
+			return filter, fmt.Errorf("Invalid --until: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+const historyDateLayout = "2006-01-02"
+
+// parseHistoryTime accepts RFC 3339 timestamps and plain dates, which are
+// taken as midnight UTC.
+func parseHistoryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(historyDateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a date (2006-01-02) nor an RFC 3339 timestamp", value)
+	}
+	return t, nil
+}
+
+// parseHistoryUntil is parseHistoryTime for the upper bound: a plain date
+// includes the whole day, up to its last nanosecond.
+func parseHistoryUntil(value string) (time.Time, error) {
+	t, err := parseHistoryTime(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if _, err := time.Parse(historyDateLayout, value); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return t, nil
+}
+
+// isBefore returns true if the migration is older than --since.
+func (f historyFilter) isBefore(migration spec.BranchMigration) bool {
+	return !f.since.IsZero() && migration.CreatedAt != nil && time.Time(*migration.CreatedAt).Before(f.since)
+}
+
+func (f historyFilter) matches(migration spec.BranchMigration) bool {
+	if migration.CreatedAt != nil {
+		createdAt := time.Time(*migration.CreatedAt)
+		if !f.since.IsZero() && createdAt.Before(f.since) {
+			return false
+		}
+		if !f.until.IsZero() && createdAt.After(f.until) {
+			return false
+		}
+	}
+	if f.gitSHA != "" && (migration.LastGitRevision == nil || !strings.HasPrefix(*migration.LastGitRevision, f.gitSHA)) {
+		return false
+	}
+	if f.table != "" && !migrationTouchesTable(migration, f.table) {
+		return false
+	}
+	return true
+}
+
+// migrationTouchesTable returns true if the migration creates, deletes,
+// renames or changes the table.
+func migrationTouchesTable(migration spec.BranchMigration, table string) bool {
+	if migration.NewTables != nil {
+		if _, ok := migration.NewTables.Get(table); ok {
+			return true
+		}
+	}
+	if migration.TableMigrations != nil {
+		if _, ok := migration.TableMigrations.Get(table); ok {
+			return true
+		}
+	}
+	for _, name := range stringList(migration.RemovedTables) {
+		if name == table {
+			return true
+		}
+	}
+	if migration.RenamedTables != nil {
+		for _, rename := range *migration.RenamedTables {
+			if rename.OldName == table || rename.NewName == table {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// migrationOneline formats a migration on a single line, like
+// `git log --oneline`: ID, date, git revision and a summary of the changes.
+func migrationOneline(migration spec.BranchMigration) string {
+	parts := []string{}
+	if migration.Id != nil {
+		parts = append(parts, *migration.Id)
+	}
+	if migration.CreatedAt != nil {
+		parts = append(parts, time.Time(*migration.CreatedAt).UTC().Format("2006-01-02 15:04"))
+	}
+	if migration.LastGitRevision != nil {
+		sha := *migration.LastGitRevision
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		if migration.LocalChanges {
+			sha += "+"
+		}
+		parts = append(parts, sha)
+	}
+	if migration.Title != nil && *migration.Title != "" {
+		parts = append(parts, *migration.Title)
+	} else {
+		parts = append(parts, migrationSummary(migration))
+	}
+	return strings.Join(parts, " ")
+}
+
+func migrationSummary(migration spec.BranchMigration) string {
+	changes := []string{}
+	if migration.NewTables != nil {
+		for _, name := range sortedTableNames(migration.NewTables.AdditionalProperties) {
+			changes = append(changes, "create "+name)
+		}
+	}
+	for _, name := range stringList(migration.RemovedTables) {
+		changes = append(changes, "delete "+name)
+	}
+	if migration.RenamedTables != nil {
+		for _, rename := range *migration.RenamedTables {
+			changes = append(changes, fmt.Sprintf("rename %s to %s", rename.OldName, rename.NewName))
+		}
+	}
+	if migration.TableMigrations != nil {
+		for _, name := range sortedTableMigrationNames(migration.TableMigrations.AdditionalProperties) {
+			tableMigration := migration.TableMigrations.AdditionalProperties[name]
+			added, modified := 0, 0
+			if tableMigration.NewColumns != nil {
+				added = len(tableMigration.NewColumns.AdditionalProperties)
+			}
+			if tableMigration.ModifiedColumns != nil {
+				modified = len(*tableMigration.ModifiedColumns)
+			}
+			removed := len(stringList(tableMigration.RemovedColumns))
+			changes = append(changes, fmt.Sprintf("alter %s (+%d -%d ~%d)", name, added, removed, modified))
+		}
+	}
+	if len(changes) == 0 {
+		return "(no changes)"
+	}
+	return strings.Join(changes, ", ")
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestHistoryFilter(t *testing.T) {
+	createdAt := spec.DateTime(time.Date(2022, 3, 15, 10, 30, 0, 0, time.UTC))
+	migration := PlanMigration(
+		spec.Schema{Tables: []spec.Table{
+			{Name: "users", Columns: []spec.Column{{Name: "age", Type: spec.ColumnTypeInt}}},
+			{Name: "legacy", Columns: []spec.Column{}},
+		}},
+		spec.Schema{Tables: []spec.Table{
+			{Name: "users", Columns: []spec.Column{{Name: "email", Type: spec.ColumnTypeEmail}}},
+			{Name: "posts", Columns: []spec.Column{}},
+		}})
+	migration.Id = stringPtr("mig_c8l4bgqt2u10pqb0adsg")
+	migration.CreatedAt = &createdAt
+	migration.LastGitRevision = stringPtr("4f2a9c0d1e2f")
+	migration.LocalChanges = true
+
+	since, err := parseHistoryTime("2022-03-01")
+	require.NoError(t, err)
+	until, err := parseHistoryUntil("2022-03-15T10:00:00Z")
+	require.NoError(t, err)
+	sameDay, err := parseHistoryUntil("2022-03-15")
+	require.NoError(t, err)
+	dayBefore, err := parseHistoryUntil("2022-03-14")
+	require.NoError(t, err)
+	_, err = parseHistoryUntil("yesterday")
+	require.Error(t, err)
+	_, err = parseHistoryTime("last week")
+	require.Error(t, err)
+
+	require.True(t, historyFilter{}.matches(migration))
+	require.True(t, historyFilter{since: since}.matches(migration))
+	require.False(t, historyFilter{since: since}.isBefore(migration))
+	require.False(t, historyFilter{until: until}.matches(migration))
+	require.True(t, historyFilter{until: sameDay}.matches(migration))
+	require.False(t, historyFilter{until: dayBefore}.matches(migration))
+	require.Equal(t, time.Date(2022, 3, 15, 23, 59, 59, 999999999, time.UTC), sameDay)
+	require.True(t, historyFilter{since: time.Time(createdAt).Add(time.Hour)}.isBefore(migration))
+	require.True(t, historyFilter{table: "legacy"}.matches(migration))
+	require.True(t, historyFilter{table: "users"}.matches(migration))
+	require.False(t, historyFilter{table: "teams"}.matches(migration))
+	require.True(t, historyFilter{gitSHA: "4f2a9c"}.matches(migration))
+	require.False(t, historyFilter{gitSHA: "abc"}.matches(migration))
+
+	require.Equal(t, "mig_c8l4bgqt2u10pqb0adsg 2022-03-15 10:30 4f2a9c0+ create posts, delete legacy, alter users (+1 -1 ~0)",
+		migrationOneline(migration))
+}
@@ -0,0 +1,441 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/urfave/cli/v2"
+)
+
+// importRow is a row of an imported file, converted to a record. err is set
+// if the row can't be converted, in which case it is rejected.
+type importRow struct {
+	line   int
+	record map[string]interface{}
+	raw    interface{}
+	err    error
+}
+
+// rowReader reads the rows of an imported file, returning io.EOF at the end.
+type rowReader interface {
+	Read() (*importRow, error)
+}
+
+// importFormat returns the format of the file, from the --format flag or the
+// file extension.
+func importFormat(filename, format string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".csv":
+			format = "csv"
+		case ".ndjson", ".jsonl":
+			format = "ndjson"
+		default:
+			return "", fmt.Errorf("Can't tell the format of %s from its extension, use --format csv or ndjson", filename)
+		}
+	}
+	if format != "csv" && format != "ndjson" {
+		return "", fmt.Errorf("Invalid --format %s: expected csv or ndjson", format)
+	}
+	return format, nil
+}
+
+// csvRowReader reads CSV files whose header row names the columns, with
+// dotted names for the columns of objects, e.g. address.zipcode. Empty
+// values are left unset.
+type csvRowReader struct {
+	reader    *csv.Reader
+	table     spec.Table
+	headers   [][]string
+	columns   []spec.Column
+	separator string
+}
+
+func newCSVRowReader(r io.Reader, table spec.Table, delimiter rune, separator string) (*csvRowReader, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("The file is empty, expected a header row")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading the header row: %w", err)
+	}
+
+	rows := &csvRowReader{reader: reader, table: table, separator: separator}
+	unknown := []string{}
+	for _, name := range header {
+		name = strings.TrimSpace(name)
+		path := strings.Split(name, ".")
+		column, ok := columnAtPath(table.Columns, path)
+		if name == "id" {
+			column, ok = spec.Column{Name: "id", Type: spec.ColumnTypeString}, true
+		}
+		if !ok {
+			unknown = append(unknown, name)
+		}
+		rows.headers = append(rows.headers, path)
+		rows.columns = append(rows.columns, column)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("Table [%s] has no columns %s", table.Name, strings.Join(unknown, ", "))
+	}
+	return rows, nil
+}
+
+func (r *csvRowReader) Read() (*importRow, error) {
+	fields, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return &importRow{line: parseErr.StartLine, err: parseErr.Err}, nil
+		}
+		return nil, err
+	}
+	line, _ := r.reader.FieldPos(0)
+
+	raw := map[string]string{}
+	values := map[string]interface{}{}
+	for i, field := range fields {
+		if i >= len(r.headers) {
+			return &importRow{line: line, raw: fields, err: fmt.Errorf("expected %d values, found %d", len(r.headers), len(fields))}, nil
+		}
+		path := r.headers[i]
+		raw[strings.Join(path, ".")] = field
+		if field == "" {
+			continue
+		}
+		var value interface{} = field
+		if r.columns[i].Type == spec.ColumnTypeMultiple && !strings.HasPrefix(strings.TrimSpace(field), "[") {
+			items := []interface{}{}
+			for _, item := range strings.Split(field, r.separator) {
+				items = append(items, strings.TrimSpace(item))
+			}
+			value = items
+		}
+		setPath(values, path, value)
+	}
+	record, err := importRecord(r.table, values)
+	return &importRow{line: line, record: record, raw: raw, err: err}, nil
+}
+
+// ndjsonRowReader reads files with one JSON record per line.
+type ndjsonRowReader struct {
+	scanner *bufio.Scanner
+	table   spec.Table
+	line    int
+}
+
+func newNDJSONRowReader(r io.Reader, table spec.Table) *ndjsonRowReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &ndjsonRowReader{scanner: scanner, table: table}
+}
+
+func (r *ndjsonRowReader) Read() (*importRow, error) {
+	for r.scanner.Scan() {
+		r.line++
+		text := strings.TrimSpace(r.scanner.Text())
+		if text == "" {
+			continue
+		}
+		values := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(text), &values); err != nil {
+			return &importRow{line: r.line, raw: text, err: fmt.Errorf("invalid JSON: %w", err)}, nil
+		}
+		record, err := importRecord(r.table, values)
+		return &importRow{line: r.line, record: record, raw: values, err: err}, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// importRecord coerces the values to the table columns, keeping the record
+// ID if there is one. The values are left untouched, as they are also what
+// gets written to the reject file.
+func importRecord(table spec.Table, values map[string]interface{}) (map[string]interface{}, error) {
+	columns := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		if name != "id" && name != "xata" {
+			columns[name] = value
+		}
+	}
+	id, hasID := values["id"]
+	record, err := coerceRecord(table.Name, table.Columns, columns)
+	if err != nil {
+		return nil, err
+	}
+	if hasID && id != nil {
+		if _, ok := id.(string); !ok {
+			return nil, fmt.Errorf("Invalid record id %s, expected a string", recordCellValue(id))
+		}
+		record["id"] = id
+	}
+	return record, nil
+}
+
+func columnAtPath(columns []spec.Column, path []string) (spec.Column, bool) {
+	column, ok := findColumn(columns, path[0])
+	if !ok || len(path) == 1 {
+		return column, ok
+	}
+	return columnAtPath(column.Columns, path[1:])
+}
+
+func setPath(values map[string]interface{}, path []string, value interface{}) {
+	for _, name := range path[:len(path)-1] {
+		nested, ok := values[name].(map[string]interface{})
+		if !ok {
+			nested = map[string]interface{}{}
+			values[name] = nested
+		}
+		values = nested
+	}
+	values[path[len(path)-1]] = value
+}
+
+// recordsRejectedError is returned by a batchInserter when the API refuses
+// the records, as opposed to errors that should stop the import.
+type recordsRejectedError struct {
+	message string
+}
+
+func (e recordsRejectedError) Error() string {
+	return e.message
+}
+
+type batchInserter func(records []map[string]interface{}) error
+
+// recordImporter sends rows in batches and rejects the rows that can't be
+// converted or that the API refuses.
+type recordImporter struct {
+	insert    batchInserter
+	reject    func(row *importRow) error
+	batchSize int
+
+	batch    []*importRow
+	imported int
+	rejected int
+}
+
+func (im *recordImporter) add(row *importRow) error {
+	if row.err != nil {
+		im.rejected++
+		return im.reject(row)
+	}
+	im.batch = append(im.batch, row)
+	if len(im.batch) >= im.batchSize {
+		return im.flush()
+	}
+	return nil
+}
+
+func (im *recordImporter) flush() error {
+	if len(im.batch) == 0 {
+		return nil
+	}
+	batch := im.batch
+	im.batch = nil
+	if err := im.insertRows(batch); err == nil || !isRejection(err) {
+		return err
+	}
+	// find the rows the API refuses by inserting them one by one
+	for _, row := range batch {
+		if err := im.insertRows([]*importRow{row}); err != nil {
+			if !isRejection(err) {
+				return err
+			}
+			row.err = err
+			im.rejected++
+			if err := im.reject(row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (im *recordImporter) insertRows(rows []*importRow) error {
+	records := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, row.record)
+	}
+	if err := im.insert(records); err != nil {
+		return err
+	}
+	im.imported += len(records)
+	return nil
+}
+
+func isRejection(err error) bool {
+	var rejected recordsRejectedError
+	return errors.As(err, &rejected)
+}
+
+// importRows reads all the rows and imports them.
+func importRows(rows rowReader, im *recordImporter) error {
+	for {
+		row, err := rows.Read()
+		if err == io.EOF {
+			return im.flush()
+		}
+		if err != nil {
+			return err
+		}
+		if err := im.add(row); err != nil {
+			return err
+		}
+	}
+}
+
+// rejectWriter writes rejected rows as NDJSON, creating the file on the
+// first rejected row.
+type rejectWriter struct {
+	filename string
+	file     *os.File
+	encoder  *json.Encoder
+}
+
+func (w *rejectWriter) write(row *importRow) error {
+	if w.file == nil {
+		file, err := os.Create(w.filename)
+		if err != nil {
+			return fmt.Errorf("creating reject file: %w", err)
+		}
+		w.file = file
+		w.encoder = json.NewEncoder(file)
+	}
+	return w.encoder.Encode(map[string]interface{}{
+		"line":  row.line,
+		"error": row.err.Error(),
+		"row":   row.raw,
+	})
+}
+
+func (w *rejectWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Close()
+}
+
+func bulkInserter(c *cli.Context, client *spec.ClientWithResponses, dbBranch spec.DBBranchNameParam, table string) batchInserter {
+	return func(records []map[string]interface{}) error {
+		resp, err := client.BulkInsertTableRecordsWithResponse(c.Context, dbBranch, spec.TableNameParam(table),
+			spec.BulkInsertTableRecordsJSONRequestBody{Records: records})
+		if err != nil {
+			return fmt.Errorf("Error inserting records: %w", err)
+		}
+		if resp.JSON401 != nil {
+			return ErrorUnauthorized{message: resp.JSON401.Message}
+		}
+		if resp.JSON400 != nil {
+			messages := []string{}
+			for _, e := range resp.JSON400.Errors {
+				if e.Message != nil {
+					messages = append(messages, *e.Message)
+				}
+			}
+			return recordsRejectedError{message: strings.Join(messages, "; ")}
+		}
+		if resp.StatusCode()/100 != 2 {
+			return fmt.Errorf("Error inserting records: %s: %s", resp.Status(), getMessage(resp.Body))
+		}
+		return nil
+	}
+}
+
+// ImportCommand imports a CSV or NDJSON file into a table.
+func ImportCommand(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return fmt.Errorf("Expected exactly two arguments: the table name and the file to import")
+	}
+	table, filename := c.Args().Get(0), c.Args().Get(1)
+	format, err := importFormat(filename, c.String("format"))
+	if err != nil {
+		return err
+	}
+	batchSize := c.Int("batch-size")
+	if batchSize < 1 {
+		return fmt.Errorf("Invalid --batch-size %d: must be at least 1", batchSize)
+	}
+	delimiter := []rune(c.String("delimiter"))
+	if len(delimiter) != 1 {
+		return fmt.Errorf("Invalid --delimiter %q: must be a single character", c.String("delimiter"))
+	}
+
+	dbBranch, dbName, branch, err := getDBBranchParam(c)
+	if err != nil {
+		return err
+	}
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+	details, err := getBranchDetails(c, dbName, branch)
+	if err != nil {
+		return err
+	}
+	schemaTable, ok := tablesByName(details.Schema)[table]
+	if !ok {
+		if !c.Bool("create-table") {
+			return fmt.Errorf("Table [%s] doesn't exist in branch [%s:%s]. Use --create-table to create it from the file.", table, dbName, branch)
+		}
+		created, err := createImportTable(c, dbName, branch, table, filename, format, delimiter[0], details.Schema)
+		if err != nil || created == nil {
+			return err
+		}
+		schemaTable = *created
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var rows rowReader
+	if format == "csv" {
+		rows, err = newCSVRowReader(bufio.NewReader(file), schemaTable, delimiter[0], c.String("multiple-separator"))
+		if err != nil {
+			return err
+		}
+	} else {
+		rows = newNDJSONRowReader(file, schemaTable)
+	}
+
+	rejects := &rejectWriter{filename: c.String("reject-file")}
+	if rejects.filename == "" {
+		rejects.filename = filename + ".rejects.ndjson"
+	}
+	im := &recordImporter{
+		insert:    bulkInserter(c, client, dbBranch, table),
+		reject:    rejects.write,
+		batchSize: batchSize,
+	}
+	err = importRows(rows, im)
+	if closeErr := rejects.close(); err == nil {
+		err = closeErr
+	}
+
+	fmt.Printf("Imported %d records into table [%s] of [%s].\n", im.imported, table, dbBranch)
+	if err != nil {
+		return err
+	}
+	if im.rejected > 0 {
+		return cli.Exit(fmt.Sprintf("%d rows were rejected, see %s", im.rejected, rejects.filename), 1)
+	}
+	return nil
+}
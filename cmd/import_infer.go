@@ -0,0 +1,482 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xataio/cli/client/spec"
+	"github.com/xataio/cli/filesystem"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/urfave/cli/v2"
+)
+
+// maxStringLength is the longest value of string columns, longer values
+// need a text column.
+const maxStringLength = 255
+
+// maxListItemLength is the longest item of a CSV value that is taken as a
+// list for a multiple column. Longer items are more likely sentences.
+const maxListItemLength = 40
+
+var emailRegexp = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// sampleCSV reads up to n rows of a CSV file, keeping the values as strings
+// and the columns in header order.
+func sampleCSV(r io.Reader, delimiter rune, n int) ([]map[string]interface{}, []string, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("The file is empty, expected a header row")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading the header row: %w", err)
+	}
+	paths := make([]string, 0, len(header))
+	for _, name := range header {
+		paths = append(paths, strings.TrimSpace(name))
+	}
+
+	records := []map[string]interface{}{}
+	for len(records) < n {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// invalid rows are rejected by the import
+			continue
+		}
+		record := map[string]interface{}{}
+		for i, field := range fields {
+			if i < len(paths) && field != "" {
+				setPath(record, strings.Split(paths[i], "."), field)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, paths, nil
+}
+
+// sampleNDJSON reads up to n records of an NDJSON file. As JSON objects are
+// unordered, the columns are in the order they are first seen, sorted by
+// name within a record.
+func sampleNDJSON(r io.Reader, n int) ([]map[string]interface{}, []string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	records := []map[string]interface{}{}
+	paths := []string{}
+	seen := map[string]bool{}
+	for len(records) < n && scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		record := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			continue
+		}
+		for _, path := range recordPaths("", record) {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return records, paths, nil
+}
+
+// recordPaths returns the dotted paths of the values of a record, objects
+// being flattened.
+func recordPaths(prefix string, values map[string]interface{}) []string {
+	paths := []string{}
+	for _, name := range sortedKeys(values) {
+		if nested, ok := values[name].(map[string]interface{}); ok && len(nested) > 0 {
+			paths = append(paths, recordPaths(prefix+name+".", nested)...)
+			continue
+		}
+		paths = append(paths, prefix+name)
+	}
+	return paths
+}
+
+// inferTable infers the columns of a table from sample records. paths gives
+// the order of the columns, as dotted paths. The id column is skipped.
+func inferTable(name string, records []map[string]interface{}, paths []string, separator string) spec.Table {
+	order := []string{}
+	for _, path := range paths {
+		if path != "id" && path != "xata" && !strings.HasPrefix(path, "xata.") {
+			order = append(order, path)
+		}
+	}
+	return spec.Table{Name: name, Columns: inferColumns(records, order, separator)}
+}
+
+func inferColumns(records []map[string]interface{}, paths []string, separator string) []spec.Column {
+	columns := []spec.Column{}
+	for _, name := range childNames(paths) {
+		values := []interface{}{}
+		objects := []map[string]interface{}{}
+		for _, record := range records {
+			value, ok := record[name]
+			if !ok || value == nil || value == "" {
+				continue
+			}
+			values = append(values, value)
+			if object, ok := value.(map[string]interface{}); ok {
+				objects = append(objects, object)
+			}
+		}
+
+		nested := []string{}
+		for _, path := range paths {
+			if strings.HasPrefix(path, name+".") {
+				nested = append(nested, strings.TrimPrefix(path, name+"."))
+			}
+		}
+		if len(nested) > 0 && len(objects) == len(values) {
+			columns = append(columns, spec.Column{
+				Name:    name,
+				Type:    spec.ColumnTypeObject,
+				Columns: inferColumns(objects, nested, separator),
+			})
+			continue
+		}
+		columns = append(columns, spec.Column{Name: name, Type: inferColumnType(values, separator)})
+	}
+	return columns
+}
+
+// childNames returns the first element of the dotted paths, in order and
+// without duplicates.
+func childNames(paths []string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, path := range paths {
+		name := strings.SplitN(path, ".", 2)[0]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// inferColumnType picks the most specific type that fits all the values:
+// bool, int, float, email, multiple, then string or text by length.
+func inferColumnType(values []interface{}, separator string) spec.ColumnType {
+	if len(values) == 0 {
+		return spec.ColumnTypeString
+	}
+	all := func(check func(value interface{}) bool) bool {
+		for _, value := range values {
+			if !check(value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case all(isBoolValue):
+		return spec.ColumnTypeBool
+	case all(isIntValue):
+		return spec.ColumnTypeInt
+	case all(isFloatValue):
+		return spec.ColumnTypeFloat
+	case all(isEmailValue):
+		return spec.ColumnTypeEmail
+	case all(isStringList) || (all(isString) && isSeparatedList(values, separator)):
+		return spec.ColumnTypeMultiple
+	}
+
+	longest := 0
+	for _, value := range values {
+		if n := len([]rune(recordCellValue(value))); n > longest {
+			longest = n
+		}
+	}
+	if longest > maxStringLength {
+		return spec.ColumnTypeText
+	}
+	return spec.ColumnTypeString
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+func isBoolValue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return true
+	case string:
+		lower := strings.ToLower(strings.TrimSpace(v))
+		return lower == "true" || lower == "false"
+	}
+	return false
+}
+
+func isIntValue(value interface{}) bool {
+	switch v := value.(type) {
+	case float64:
+		return v == math.Trunc(v)
+	case string:
+		_, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return err == nil && !hasLeadingZero(v)
+	}
+	return false
+}
+
+func isFloatValue(value interface{}) bool {
+	switch v := value.(type) {
+	case float64:
+		return true
+	case string:
+		_, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return err == nil && !hasLeadingZero(v)
+	}
+	return false
+}
+
+// hasLeadingZero returns true for numbers like zip codes, which are strings
+// as the leading zeros matter.
+func hasLeadingZero(s string) bool {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "-")
+	return len(s) > 1 && s[0] == '0' && s[1] != '.'
+}
+
+func isEmailValue(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && emailRegexp.MatchString(strings.TrimSpace(s))
+}
+
+// isStringList returns true for JSON arrays of strings, or CSV values that
+// are JSON arrays of strings.
+func isStringList(value interface{}) bool {
+	if s, ok := value.(string); ok {
+		if !strings.HasPrefix(strings.TrimSpace(s), "[") {
+			return false
+		}
+		var list []interface{}
+		if err := json.Unmarshal([]byte(s), &list); err != nil {
+			return false
+		}
+		value = list
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, item := range list {
+		if _, ok := item.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// isSeparatedList returns true if the CSV values look like lists: at least
+// one has the separator and all the items are short.
+func isSeparatedList(values []interface{}, separator string) bool {
+	if separator == "" {
+		return false
+	}
+	hasSeparator := false
+	for _, value := range values {
+		s := value.(string)
+		if strings.Contains(s, separator) {
+			hasSeparator = true
+		}
+		for _, item := range strings.Split(s, separator) {
+			if len([]rune(strings.TrimSpace(item))) > maxListItemLength {
+				return false
+			}
+		}
+	}
+	return hasSeparator
+}
+
+// sampleImportFile infers a table from the first rows of the file.
+func sampleImportFile(filename, format, table string, delimiter rune, separator string, n int) (spec.Table, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return spec.Table{}, err
+	}
+	defer file.Close()
+
+	var records []map[string]interface{}
+	var paths []string
+	if format == "csv" {
+		records, paths, err = sampleCSV(bufio.NewReader(file), delimiter, n)
+	} else {
+		records, paths, err = sampleNDJSON(file, n)
+	}
+	if err != nil {
+		return spec.Table{}, err
+	}
+	if len(records) == 0 {
+		return spec.Table{}, fmt.Errorf("No valid records in %s to infer the columns from", filename)
+	}
+	return inferTable(table, records, paths, separator), nil
+}
+
+// createImportTable creates the table an import targets. The table is taken
+// from the local schema file if it is defined there, or inferred from a
+// sample of the file. It is then deployed to the branch, and an inferred
+// table is added to the schema file. It returns nil if the user cancels.
+func createImportTable(c *cli.Context, dbName, branch, table, filename, format string, delimiter rune,
+	current spec.Schema) (*spec.Table, error) {
+	force := c.Bool("force")
+	if interactive, reason := isInteractiveWithReason(c); !force && !interactive {
+		return nil, fmt.Errorf("Creating the table asks for confirmation but %s. Use --force to create it without asking.", reason)
+	}
+	if !spec.IsValidIdentifier(table) {
+		return nil, fmt.Errorf("Invalid table name [%s]", table)
+	}
+
+	dir := c.String("dir")
+	settings, err := ReadSettings(dir)
+	if err != nil {
+		return nil, err
+	}
+	schemaFile := SchemaFilePath(dir, settings)
+	local, _, err := readSchemaFile(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	newTable, inSchemaFile := tablesByName(local)[table]
+	if inSchemaFile {
+		fmt.Printf("Table [%s] is defined in %s but not deployed to [%s:%s]:\n", table, schemaFile, dbName, branch)
+	} else {
+		newTable, err = sampleImportFile(filename, format, table, delimiter, c.String("multiple-separator"), c.Int("sample-size"))
+		if err != nil {
+			return nil, err
+		}
+		if err := validateInferredTable(newTable, filename); err != nil {
+			return nil, err
+		}
+		fmt.Printf("Table [%s] inferred from %s:\n", table, filename)
+	}
+	printColumnDefinitions("  ", newTable.Columns)
+	fmt.Println()
+
+	if !force {
+		message := fmt.Sprintf("Add table [%s] to %s, deploy it to [%s:%s] and import the file?", table, schemaFile, dbName, branch)
+		if settings.SchemaFileFormat != SettingsXata {
+			message = fmt.Sprintf("Rewrite %s with table [%s] added (keeping a backup in %s), deploy it to [%s:%s] and import the file?",
+				schemaFile, table, schemaFile+backupSuffix, dbName, branch)
+		}
+		if inSchemaFile {
+			message = fmt.Sprintf("Deploy table [%s] to [%s:%s] and import the file?", table, dbName, branch)
+		}
+		yes := false
+		survey.AskOne(&survey.Confirm{Message: message, Default: true}, &yes)
+		if !yes {
+			return nil, nil
+		}
+	}
+
+	// only deploy the new table, not other pending changes of the schema file
+	target := current
+	target.Tables = append(append([]spec.Table{}, current.Tables...), newTable)
+	plan, _, err := planDeploy(c, dbName, branch, target, false)
+	if err != nil {
+		return nil, err
+	}
+	setMigrationGitRevision(&plan.Migration, schemaFile)
+	if err := executeMigrationPlan(c, dbName, branch, plan); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Table [%s] created in [%s:%s]\n", table, dbName, branch)
+
+	// the schema file is only changed once the table exists, so a failed
+	// deploy doesn't leave a table the server refused in it
+	if !inSchemaFile {
+		backup, err := addTableToSchemaFile(schemaFile, settings.SchemaFileFormat, local, newTable)
+		if err != nil {
+			return nil, fmt.Errorf("Table [%s] was created, but adding it to %s failed: %w", table, schemaFile, err)
+		}
+		if backup != "" {
+			fmt.Printf("Added table [%s] to %s, the previous version is in %s\n", table, schemaFile, backup)
+		} else {
+			fmt.Printf("Added table [%s] to %s\n", table, schemaFile)
+		}
+	}
+	return &newTable, nil
+}
+
+// validateInferredTable checks the names taken from the headers or keys of
+// the file, before anything is written or deployed.
+func validateInferredTable(table spec.Table, filename string) error {
+	diagnostics := ValidateSchema(spec.Schema{FormatVersion: schemaVersion, Tables: []spec.Table{table}})
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	problems := make([]string, 0, len(diagnostics))
+	for _, diagnostic := range diagnostics {
+		problems = append(problems, diagnostic.Message)
+	}
+	return fmt.Errorf("Can't create table [%s] from the columns of %s:\n  %s\nRename them in the file, or create the table first.",
+		table.Name, filename, strings.Join(problems, "\n  "))
+}
+
+// addTableToSchemaFile adds a table to the schema file. The table is
+// appended to .xata files, which keeps their comments and layout. Other
+// formats are written again from the schema, after a copy of the file is
+// made; its name is returned.
+func addTableToSchemaFile(schemaFile, format string, local spec.Schema, table spec.Table) (string, error) {
+	if format == SettingsXata {
+		existing, err := ioutil.ReadFile(schemaFile)
+		if err != nil {
+			return "", fmt.Errorf("reading file: %w", err)
+		}
+		out := append([]byte{}, existing...)
+		if len(out) > 0 {
+			if out[len(out)-1] != '\n' {
+				out = append(out, '\n')
+			}
+			out = append(out, '\n')
+		}
+		out = append(out, FormatSchemaDSL(spec.Schema{Tables: []spec.Table{table}})...)
+		if _, err := ParseSchemaDSL(schemaFile, out); err != nil {
+			return "", fmt.Errorf("The schema file can't be read with the table appended: %w", err)
+		}
+		if err := filesystem.WriteFileAtomic(schemaFile, out, 0644); err != nil {
+			return "", fmt.Errorf("writing file: %w", err)
+		}
+		return "", nil
+	}
+
+	local.Tables = append(append([]spec.Table{}, local.Tables...), table)
+	local.FormatVersion = schemaVersion
+	out, err := marshalSchema(format, local)
+	if err != nil {
+		return "", err
+	}
+	backup := schemaFile + backupSuffix
+	if err := copyFile(schemaFile, backup); err != nil {
+		return "", fmt.Errorf("creating backup: %w", err)
+	}
+	if err := filesystem.WriteFileAtomic(schemaFile, out, 0644); err != nil {
+		return "", fmt.Errorf("writing file: %w", err)
+	}
+	return backup, nil
+}
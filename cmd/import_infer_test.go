@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestInferTableFromCSV(t *testing.T) {
+	data := "id,name,email,age,score,active,zip,tags,bio,address.city,address.number,empty\n" +
+		"rec_1,Alice,alice@example.com,42,1.5,true,01234,a;b,short,Berlin,12,\n" +
+		"rec_2,Bob,bob@example.com,,2,FALSE,98765,c,\"" + strings.Repeat("long ", 60) + "\",Paris,,\n"
+	records, paths, err := sampleCSV(strings.NewReader(data), ',', 10)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	table := inferTable("users", records, paths, ";")
+	require.Equal(t, spec.Table{Name: "users", Columns: []spec.Column{
+		{Name: "name", Type: spec.ColumnTypeString},
+		{Name: "email", Type: spec.ColumnTypeEmail},
+		{Name: "age", Type: spec.ColumnTypeInt},
+		{Name: "score", Type: spec.ColumnTypeFloat},
+		{Name: "active", Type: spec.ColumnTypeBool},
+		{Name: "zip", Type: spec.ColumnTypeString},
+		{Name: "tags", Type: spec.ColumnTypeMultiple},
+		{Name: "bio", Type: spec.ColumnTypeText},
+		{Name: "address", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+			{Name: "city", Type: spec.ColumnTypeString},
+			{Name: "number", Type: spec.ColumnTypeInt},
+		}},
+		{Name: "empty", Type: spec.ColumnTypeString},
+	}}, table)
+
+	// the inferred table can import the file
+	rows, err := newCSVRowReader(strings.NewReader(data), table, ',', ";")
+	require.NoError(t, err)
+	for _, row := range readAllRows(t, rows) {
+		require.NoError(t, row.err)
+	}
+}
+
+func TestInferTableFromNDJSON(t *testing.T) {
+	data := `{"id":"rec_1","name":"Alice","age":42,"tags":["a","b"],"address":{"zip":"01234","geo":{"lat":52.5}}}
+{"name":"Bob","age":7,"score":3,"tags":[],"note":"a; sentence that is clearly not a list of short tags at all"}
+not json
+`
+	records, paths, err := sampleNDJSON(strings.NewReader(data), 10)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	table := inferTable("users", records, paths, ";")
+	require.Equal(t, []spec.Column{
+		{Name: "address", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+			{Name: "geo", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+				{Name: "lat", Type: spec.ColumnTypeFloat},
+			}},
+			{Name: "zip", Type: spec.ColumnTypeString},
+		}},
+		{Name: "age", Type: spec.ColumnTypeInt},
+		{Name: "name", Type: spec.ColumnTypeString},
+		{Name: "tags", Type: spec.ColumnTypeMultiple},
+		{Name: "note", Type: spec.ColumnTypeString},
+		{Name: "score", Type: spec.ColumnTypeInt},
+	}, table.Columns)
+
+	records, _, err = sampleNDJSON(strings.NewReader(data), 1)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+}
+
+func TestValidateInferredTable(t *testing.T) {
+	records, paths, err := sampleCSV(strings.NewReader("First Name,e-mail?,age\nAlice,a@example.com,42\n"), ',', 10)
+	require.NoError(t, err)
+	table := inferTable("people", records, paths, ";")
+
+	require.EqualError(t, validateInferredTable(table, "people.csv"),
+		"Can't create table [people] from the columns of people.csv:\n"+
+			"  invalid column name \"First Name\"\n"+
+			"  invalid column name \"e-mail?\"\n"+
+			"Rename them in the file, or create the table first.")
+
+	records, paths, err = sampleCSV(strings.NewReader("first_name,e-mail\nAlice,a@example.com\n"), ',', 10)
+	require.NoError(t, err)
+	require.NoError(t, validateInferredTable(inferTable("people", records, paths, ";"), "people.csv"))
+}
+
+func TestAddTableToSchemaFile(t *testing.T) {
+	table := spec.Table{Name: "people", Columns: []spec.Column{{Name: "name", Type: spec.ColumnTypeString}}}
+
+	dir := t.TempDir()
+	xataFile := filepath.Join(dir, "schema.xata")
+	original := "// the users of the app\ntable users {\n  email   email   // Email\n}"
+	require.NoError(t, ioutil.WriteFile(xataFile, []byte(original), 0644))
+	local, err := ParseSchemaDSL(xataFile, []byte(original))
+	require.NoError(t, err)
+
+	backup, err := addTableToSchemaFile(xataFile, SettingsXata, local, table)
+	require.NoError(t, err)
+	require.Empty(t, backup)
+	out, err := ioutil.ReadFile(xataFile)
+	require.NoError(t, err)
+	require.Equal(t, original+"\n\ntable people {\n    name string\n}\n", string(out))
+
+	yamlFile := filepath.Join(dir, "schema.yaml")
+	data, err := marshalSchema(SettingsYAML, local)
+	require.NoError(t, err)
+	original = "# the users of the app\n" + string(data)
+	require.NoError(t, ioutil.WriteFile(yamlFile, []byte(original), 0644))
+
+	backup, err = addTableToSchemaFile(yamlFile, SettingsYAML, local, table)
+	require.NoError(t, err)
+	require.Equal(t, yamlFile+".bak", backup)
+	saved, err := ioutil.ReadFile(backup)
+	require.NoError(t, err)
+	require.Equal(t, original, string(saved))
+	rewritten, err := readSchemaFileWithFormat(yamlFile)
+	require.NoError(t, err)
+	require.Equal(t, []string{"users", "people"}, []string{rewritten.Tables[0].Name, rewritten.Tables[1].Name})
+}
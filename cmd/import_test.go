@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readAllRows(t *testing.T, rows rowReader) []*importRow {
+	all := []*importRow{}
+	for {
+		row, err := rows.Read()
+		if err == io.EOF {
+			return all
+		}
+		require.NoError(t, err)
+		all = append(all, row)
+	}
+}
+
+func TestCSVRowReader(t *testing.T) {
+	data := "id,name,age,active,tags,address.zipcode\n" +
+		"rec_1,Alice,42,true,a; b,123\n" +
+		"rec_2,Bob,old,false,,\n" +
+		"rec_3,\"Carol, Jr\",,,,\n"
+	rows, err := newCSVRowReader(strings.NewReader(data), usersTable, ',', ";")
+	require.NoError(t, err)
+	all := readAllRows(t, rows)
+	require.Len(t, all, 3)
+
+	require.NoError(t, all[0].err)
+	require.Equal(t, 2, all[0].line)
+	require.Equal(t, map[string]interface{}{
+		"id":      "rec_1",
+		"name":    "Alice",
+		"age":     int64(42),
+		"active":  true,
+		"tags":    []string{"a", "b"},
+		"address": map[string]interface{}{"zipcode": int64(123)},
+	}, all[0].record)
+
+	require.Equal(t, 3, all[1].line)
+	require.EqualError(t, all[1].err, "Invalid value old for column users.age of type int")
+	require.Equal(t, "old", all[1].raw.(map[string]string)["age"])
+
+	require.NoError(t, all[2].err)
+	require.Equal(t, map[string]interface{}{"id": "rec_3", "name": "Carol, Jr"}, all[2].record)
+
+	_, err = newCSVRowReader(strings.NewReader("name,nickname,address.city\n"), usersTable, ',', ";")
+	require.EqualError(t, err, "Table [users] has no columns nickname, address.city")
+}
+
+func TestNDJSONRowReader(t *testing.T) {
+	data := `{"name":"Alice","age":42}
+
+{"name":"Bob","age":"x"}
+{not json}
+{"id":"rec_9","tags":["a"]}
+`
+	all := readAllRows(t, newNDJSONRowReader(strings.NewReader(data), usersTable))
+	require.Len(t, all, 4)
+	require.Equal(t, map[string]interface{}{"name": "Alice", "age": int64(42)}, all[0].record)
+	require.Equal(t, 3, all[1].line)
+	require.Error(t, all[1].err)
+	require.Equal(t, 4, all[2].line)
+	require.Contains(t, all[2].err.Error(), "invalid JSON")
+	require.Equal(t, map[string]interface{}{"id": "rec_9", "tags": []string{"a"}}, all[3].record)
+}
+
+func TestNDJSONRejectKeepsID(t *testing.T) {
+	data := `{"id":"rec_1","xata":{"version":3},"name":"Bob","age":"x"}` + "\n"
+	all := readAllRows(t, newNDJSONRowReader(strings.NewReader(data), usersTable))
+	require.Len(t, all, 1)
+	require.Error(t, all[0].err)
+
+	rejects := &rejectWriter{filename: filepath.Join(t.TempDir(), "rejects.ndjson")}
+	require.NoError(t, rejects.write(all[0]))
+	require.NoError(t, rejects.close())
+
+	out, err := ioutil.ReadFile(rejects.filename)
+	require.NoError(t, err)
+	var rejected struct {
+		Row map[string]interface{} `json:"row"`
+	}
+	require.NoError(t, json.Unmarshal(out, &rejected))
+	require.Equal(t, "rec_1", rejected.Row["id"])
+	require.Equal(t, map[string]interface{}{"version": float64(3)}, rejected.Row["xata"])
+	require.Equal(t, "Bob", rejected.Row["name"])
+}
+
+func TestRecordImporter(t *testing.T) {
+	batches := [][]string{}
+	insert := func(records []map[string]interface{}) error {
+		names := []string{}
+		for _, record := range records {
+			if record["name"] == "bad" {
+				return recordsRejectedError{message: "invalid record"}
+			}
+			names = append(names, record["name"].(string))
+		}
+		batches = append(batches, names)
+		return nil
+	}
+	rejected := []int{}
+	im := &recordImporter{
+		insert: insert,
+		reject: func(row *importRow) error {
+			rejected = append(rejected, row.line)
+			return nil
+		},
+		batchSize: 2,
+	}
+
+	rows := []*importRow{
+		{line: 2, record: map[string]interface{}{"name": "a"}},
+		{line: 3, record: map[string]interface{}{"name": "b"}},
+		{line: 4, record: map[string]interface{}{"name": "bad"}},
+		{line: 5, err: errors.New("invalid value")},
+		{line: 6, record: map[string]interface{}{"name": "c"}},
+		{line: 7, record: map[string]interface{}{"name": "d"}},
+	}
+	for _, row := range rows {
+		require.NoError(t, im.add(row))
+	}
+	require.NoError(t, im.flush())
+
+	require.Equal(t, [][]string{{"a", "b"}, {"c"}, {"d"}}, batches)
+	require.Equal(t, []int{5, 4}, rejected)
+	require.Equal(t, 4, im.imported)
+	require.Equal(t, 2, im.rejected)
+
+	// other errors stop the import
+	im = &recordImporter{
+		insert:    func([]map[string]interface{}) error { return errors.New("unauthorized") },
+		reject:    func(*importRow) error { return nil },
+		batchSize: 1,
+	}
+	require.EqualError(t, im.add(rows[0]), "unauthorized")
+}
+
+func TestImportFormat(t *testing.T) {
+	format, err := importFormat("data.CSV", "")
+	require.NoError(t, err)
+	require.Equal(t, "csv", format)
+	format, err = importFormat("data.jsonl", "")
+	require.NoError(t, err)
+	require.Equal(t, "ndjson", format)
+	format, err = importFormat("data.txt", "csv")
+	require.NoError(t, err)
+	require.Equal(t, "csv", format)
+	_, err = importFormat("data.txt", "")
+	require.Error(t, err)
+	_, err = importFormat("data.csv", "xml")
+	require.Error(t, err)
+}
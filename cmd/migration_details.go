@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xataio/cli/client/spec"
+)
+
+// printColumnDefinitions prints one line per column with its type and
+// options, and the nested columns of objects one level deeper.
+func printColumnDefinitions(indent string, columns []spec.Column) {
+	for _, column := range columns {
+		fmt.Printf("%s%s: %s\n", indent, column.Name, columnDefinition(column))
+		if column.Type == spec.ColumnTypeObject {
+			printColumnDefinitions(indent+"  ", column.Columns)
+		}
+	}
+}
+
+// columnDefinition describes the type and options of a column, e.g.
+// `link to teams, required`.
+func columnDefinition(column spec.Column) string {
+	parts := []string{column.Type.String()}
+	if column.Type == spec.ColumnTypeLink && column.Link != nil {
+		parts[0] = "link to " + column.Link.Table
+	}
+	if column.Required {
+		parts = append(parts, "required")
+	}
+	if column.Unique {
+		parts = append(parts, "unique")
+	}
+	if column.DefaultValue != "" {
+		parts = append(parts, "default "+column.DefaultValue)
+	}
+	if column.Description != "" {
+		parts = append(parts, "description "+strconv.Quote(column.Description))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// columnChanges lists what changes between two versions of a column, one
+// `attribute: old → new` line per changed attribute. Changes of nested
+// columns are prefixed with their path in the object.
+func columnChanges(path string, old, new spec.Column) []string {
+	changes := []string{}
+	report := func(attribute, from, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s%s: %s → %s", path, attribute, from, to))
+		}
+	}
+
+	report("type", old.Type.String(), new.Type.String())
+	report("required", strconv.FormatBool(old.Required), strconv.FormatBool(new.Required))
+	report("unique", strconv.FormatBool(old.Unique), strconv.FormatBool(new.Unique))
+	report("link", linkTable(old), linkTable(new))
+	report("default", defaultValue(old), defaultValue(new))
+	report("description", strconv.Quote(old.Description), strconv.Quote(new.Description))
+
+	if old.Type != spec.ColumnTypeObject || new.Type != spec.ColumnTypeObject {
+		return changes
+	}
+	for _, oldColumn := range old.Columns {
+		newColumn, ok := findColumn(new.Columns, oldColumn.Name)
+		if !ok {
+			changes = append(changes, fmt.Sprintf("%sremoved column %s", path, oldColumn.Name))
+			continue
+		}
+		changes = append(changes, columnChanges(path+oldColumn.Name+".", oldColumn, newColumn)...)
+	}
+	for _, newColumn := range new.Columns {
+		if _, ok := findColumn(old.Columns, newColumn.Name); !ok {
+			changes = append(changes, fmt.Sprintf("%sadded column %s: %s", path, newColumn.Name, columnDefinition(newColumn)))
+		}
+	}
+	return changes
+}
+
+func defaultValue(column spec.Column) string {
+	if column.DefaultValue == "" {
+		return "-"
+	}
+	return column.DefaultValue
+}
+
+func linkTable(column spec.Column) string {
+	if column.Link == nil {
+		return "-"
+	}
+	return column.Link.Table
+}
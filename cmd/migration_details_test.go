@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestColumnDefinition(t *testing.T) {
+	require.Equal(t, "string", columnDefinition(spec.Column{Name: "name", Type: spec.ColumnTypeString}))
+	require.Equal(t, `link to teams, required, unique, description "The team"`, columnDefinition(spec.Column{
+		Name: "team", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "teams"},
+		Required: true, Unique: true, Description: "The team",
+	}))
+}
+
+func TestColumnChanges(t *testing.T) {
+	old := spec.Column{Name: "address", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+		{Name: "street", Type: spec.ColumnTypeString},
+		{Name: "zip", Type: spec.ColumnTypeInt},
+		{Name: "owner", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "users"}},
+	}}
+	new := spec.Column{Name: "location", Type: spec.ColumnTypeObject, Description: "Where", Columns: []spec.Column{
+		{Name: "zip", Type: spec.ColumnTypeString, Required: true, DefaultValue: "00000"},
+		{Name: "owner", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "people"}},
+		{Name: "country", Type: spec.ColumnTypeString, Unique: true},
+	}}
+
+	require.Equal(t, []string{
+		`description: "" → "Where"`,
+		"removed column street",
+		"zip.type: int → string",
+		"zip.required: false → true",
+		"zip.default: - → 00000",
+		"owner.link: users → people",
+		"added column country: string, unique",
+	}, columnChanges("", old, new))
+
+	require.Empty(t, columnChanges("", old, old))
+}
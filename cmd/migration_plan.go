@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/xataio/cli/client/spec"
+)
+
+// PlanMigration computes the migration that turns the from schema into the
+// to schema, without contacting the server. The result has the same shape as
+// the plan returned by GetBranchMigrationPlan: tables and columns are matched
+// by name, except for the renames declared with renamedFrom in the to schema.
+func PlanMigration(from, to spec.Schema) spec.BranchMigration {
+	tableRenames, columnRenames := renameHints(to)
+	migration := planMigrationByName(stripRenameHints(from), stripRenameHints(to))
+	applyRenames(&migration, from, to, tableRenames, columnRenames)
+	return migration
+}
+
+func planMigrationByName(from, to spec.Schema) spec.BranchMigration {
+	migration := spec.BranchMigration{NewTableOrder: []string{}}
+
+	fromTables := map[string]spec.Table{}
+	for _, table := range from.Tables {
+		fromTables[table.Name] = table
+	}
+	toTables := map[string]bool{}
+
+	for _, table := range to.Tables {
+		toTables[table.Name] = true
+		migration.NewTableOrder = append(migration.NewTableOrder, table.Name)
+
+		old, exists := fromTables[table.Name]
+		if !exists {
+			if migration.NewTables == nil {
+				migration.NewTables = &spec.BranchMigration_NewTables{}
+			}
+			migration.NewTables.Set(table.Name, table)
+			continue
+		}
+
+		tableMigration, changed := planTableMigration(old, table)
+		if !changed {
+			continue
+		}
+		if migration.TableMigrations == nil {
+			migration.TableMigrations = &spec.BranchMigration_TableMigrations{}
+		}
+		migration.TableMigrations.Set(table.Name, tableMigration)
+	}
+
+	for _, table := range from.Tables {
+		if toTables[table.Name] {
+			continue
+		}
+		if migration.RemovedTables == nil {
+			migration.RemovedTables = &[]string{}
+		}
+		*migration.RemovedTables = append(*migration.RemovedTables, table.Name)
+	}
+
+	return migration
+}
+
+func planTableMigration(from, to spec.Table) (spec.TableMigration, bool) {
+	migration := spec.TableMigration{NewColumnOrder: []string{}}
+	changed := false
+
+	fromColumns := map[string]spec.Column{}
+	for _, column := range from.Columns {
+		fromColumns[column.Name] = column
+	}
+	toColumns := map[string]bool{}
+
+	for _, column := range to.Columns {
+		toColumns[column.Name] = true
+		migration.NewColumnOrder = append(migration.NewColumnOrder, column.Name)
+
+		old, exists := fromColumns[column.Name]
+		if !exists {
+			if migration.NewColumns == nil {
+				migration.NewColumns = &spec.TableMigration_NewColumns{}
+			}
+			migration.NewColumns.Set(column.Name, column)
+			changed = true
+			continue
+		}
+
+		if !columnsEqual(old, column) {
+			if migration.ModifiedColumns == nil {
+				migration.ModifiedColumns = &[]spec.ColumnMigration{}
+			}
+			*migration.ModifiedColumns = append(*migration.ModifiedColumns, spec.ColumnMigration{Old: old, New: column})
+			changed = true
+		}
+	}
+
+	for _, column := range from.Columns {
+		if toColumns[column.Name] {
+			continue
+		}
+		if migration.RemovedColumns == nil {
+			migration.RemovedColumns = &[]string{}
+		}
+		*migration.RemovedColumns = append(*migration.RemovedColumns, column.Name)
+		changed = true
+	}
+
+	// a reorder of the remaining columns is a change on its own
+	if !changed && !reflect.DeepEqual(columnNames(from.Columns), migration.NewColumnOrder) {
+		changed = true
+	}
+
+	return migration, changed
+}
+
+func columnNames(columns []spec.Column) []string {
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
+	return names
+}
+
+func columnsEqual(a, b spec.Column) bool {
+	return reflect.DeepEqual(normalizeColumns([]spec.Column{a}), normalizeColumns([]spec.Column{b}))
+}
+
+// isMigrationEmpty returns true if the migration has nothing to apply.
+func isMigrationEmpty(migration spec.BranchMigration) bool {
+	return (migration.NewTables == nil || len(migration.NewTables.AdditionalProperties) == 0) &&
+		(migration.RemovedTables == nil || len(*migration.RemovedTables) == 0) &&
+		(migration.RenamedTables == nil || len(*migration.RenamedTables) == 0) &&
+		(migration.TableMigrations == nil || len(migration.TableMigrations.AdditionalProperties) == 0)
+}
+
+// compareMigrations lists the differences between two migration plans,
+// ignoring the order of tables and columns in the added, removed and
+// modified lists. The names are used to tell the plans apart in the
+// messages. It returns nil if both plans do the same thing.
+func compareMigrations(nameA string, a spec.BranchMigration, nameB string, b spec.BranchMigration) []string {
+	diffs := []string{}
+	report := func(format string, args ...interface{}) {
+		diffs = append(diffs, fmt.Sprintf(format, args...))
+	}
+
+	newTablesA, newTablesB := map[string]spec.Table{}, map[string]spec.Table{}
+	if a.NewTables != nil {
+		newTablesA = a.NewTables.AdditionalProperties
+	}
+	if b.NewTables != nil {
+		newTablesB = b.NewTables.AdditionalProperties
+	}
+	for _, name := range unionKeys(sortedTableNames(newTablesA), sortedTableNames(newTablesB)) {
+		tableA, inA := newTablesA[name]
+		tableB, inB := newTablesB[name]
+		switch {
+		case !inB:
+			report("only the %s plan creates table %s", nameA, name)
+		case !inA:
+			report("only the %s plan creates table %s", nameB, name)
+		case !reflect.DeepEqual(normalizeSchema(spec.Schema{Tables: []spec.Table{tableA}}),
+			normalizeSchema(spec.Schema{Tables: []spec.Table{tableB}})):
+			report("table %s is created with different columns", name)
+		}
+	}
+
+	compareNameSets(report, nameA, nameB, "deletes table", stringList(a.RemovedTables), stringList(b.RemovedTables))
+
+	renamesA, renamesB := []string{}, []string{}
+	if a.RenamedTables != nil {
+		for _, rename := range *a.RenamedTables {
+			renamesA = append(renamesA, rename.OldName+" TO "+rename.NewName)
+		}
+	}
+	if b.RenamedTables != nil {
+		for _, rename := range *b.RenamedTables {
+			renamesB = append(renamesB, rename.OldName+" TO "+rename.NewName)
+		}
+	}
+	compareNameSets(report, nameA, nameB, "renames table", renamesA, renamesB)
+
+	tablesA, tablesB := map[string]spec.TableMigration{}, map[string]spec.TableMigration{}
+	if a.TableMigrations != nil {
+		tablesA = a.TableMigrations.AdditionalProperties
+	}
+	if b.TableMigrations != nil {
+		tablesB = b.TableMigrations.AdditionalProperties
+	}
+	for _, name := range unionKeys(sortedTableMigrationNames(tablesA), sortedTableMigrationNames(tablesB)) {
+		tableReport := func(format string, args ...interface{}) {
+			report("table %s: %s", name, fmt.Sprintf(format, args...))
+		}
+		compareTableMigrations(tableReport, nameA, tablesA[name], nameB, tablesB[name])
+	}
+
+	if len(diffs) == 0 {
+		return nil
+	}
+	return diffs
+}
+
+func compareTableMigrations(report func(format string, args ...interface{}),
+	nameA string, a spec.TableMigration, nameB string, b spec.TableMigration) {
+	newA, newB := map[string]spec.Column{}, map[string]spec.Column{}
+	if a.NewColumns != nil {
+		newA = a.NewColumns.AdditionalProperties
+	}
+	if b.NewColumns != nil {
+		newB = b.NewColumns.AdditionalProperties
+	}
+	for _, name := range unionKeys(sortedColumnNames(newA), sortedColumnNames(newB)) {
+		columnA, inA := newA[name]
+		columnB, inB := newB[name]
+		switch {
+		case !inB:
+			report("only the %s plan adds column %s", nameA, name)
+		case !inA:
+			report("only the %s plan adds column %s", nameB, name)
+		case !columnsEqual(columnA, columnB):
+			report("column %s is added with different settings", name)
+		}
+	}
+
+	compareNameSets(report, nameA, nameB, "deletes column", stringList(a.RemovedColumns), stringList(b.RemovedColumns))
+
+	modifiedA, modifiedB := map[string]spec.ColumnMigration{}, map[string]spec.ColumnMigration{}
+	if a.ModifiedColumns != nil {
+		for _, column := range *a.ModifiedColumns {
+			modifiedA[column.Old.Name] = column
+		}
+	}
+	if b.ModifiedColumns != nil {
+		for _, column := range *b.ModifiedColumns {
+			modifiedB[column.Old.Name] = column
+		}
+	}
+	for _, name := range unionKeys(sortedColumnMigrationNames(modifiedA), sortedColumnMigrationNames(modifiedB)) {
+		columnA, inA := modifiedA[name]
+		columnB, inB := modifiedB[name]
+		switch {
+		case !inB:
+			report("only the %s plan modifies column %s", nameA, name)
+		case !inA:
+			report("only the %s plan modifies column %s", nameB, name)
+		case !columnsEqual(columnA.New, columnB.New):
+			report("column %s is modified differently", name)
+		}
+	}
+
+	if len(a.NewColumnOrder) > 0 && len(b.NewColumnOrder) > 0 &&
+		!reflect.DeepEqual(a.NewColumnOrder, b.NewColumnOrder) {
+		report("column order is %v in the %s plan but %v in the %s plan", a.NewColumnOrder, nameA, b.NewColumnOrder, nameB)
+	}
+}
+
+func compareNameSets(report func(format string, args ...interface{}), nameA, nameB, what string, a, b []string) {
+	inA, inB := map[string]bool{}, map[string]bool{}
+	for _, name := range a {
+		inA[name] = true
+	}
+	for _, name := range b {
+		inB[name] = true
+	}
+	for _, name := range unionKeys(a, b) {
+		switch {
+		case !inB[name]:
+			report("only the %s plan %s %s", nameA, what, name)
+		case !inA[name]:
+			report("only the %s plan %s %s", nameB, what, name)
+		}
+	}
+}
+
+func sortedColumnMigrationNames(columns map[string]spec.ColumnMigration) []string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unionKeys returns the sorted, deduplicated union of both lists.
+func unionKeys(a, b []string) []string {
+	seen := map[string]bool{}
+	res := []string{}
+	for _, list := range [][]string{a, b} {
+		for _, name := range list {
+			if !seen[name] {
+				seen[name] = true
+				res = append(res, name)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func stringList(list *[]string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return *list
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestPlanMigration(t *testing.T) {
+	from := spec.Schema{
+		Tables: []spec.Table{
+			{Name: "teams", Columns: []spec.Column{
+				{Name: "name", Type: spec.ColumnTypeString},
+				{Name: "labels", Type: spec.ColumnTypeMultiple},
+			}},
+			{Name: "users", Columns: []spec.Column{
+				{Name: "email", Type: spec.ColumnTypeEmail},
+				{Name: "age", Type: spec.ColumnTypeInt},
+			}},
+			{Name: "legacy", Columns: []spec.Column{}},
+		},
+	}
+	to := spec.Schema{
+		Tables: []spec.Table{
+			{Name: "teams", Columns: []spec.Column{
+				{Name: "name", Type: spec.ColumnTypeString, Unique: true},
+				{Name: "owner", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "users"}},
+			}},
+			{Name: "users", Columns: []spec.Column{
+				// only reordered
+				{Name: "age", Type: spec.ColumnTypeInt},
+				{Name: "email", Type: spec.ColumnTypeEmail, String: &spec.ColumnString{}},
+			}},
+			{Name: "posts", Columns: []spec.Column{
+				{Name: "title", Type: spec.ColumnTypeString},
+			}},
+		},
+	}
+
+	migration := PlanMigration(from, to)
+
+	expected := spec.BranchMigration{
+		NewTableOrder:   []string{"teams", "users", "posts"},
+		NewTables:       &spec.BranchMigration_NewTables{},
+		RemovedTables:   &[]string{"legacy"},
+		TableMigrations: &spec.BranchMigration_TableMigrations{},
+	}
+	expected.NewTables.Set("posts", to.Tables[2])
+	teams := spec.TableMigration{
+		NewColumnOrder:  []string{"name", "owner"},
+		NewColumns:      &spec.TableMigration_NewColumns{},
+		RemovedColumns:  &[]string{"labels"},
+		ModifiedColumns: &[]spec.ColumnMigration{{Old: from.Tables[0].Columns[0], New: to.Tables[0].Columns[0]}},
+	}
+	teams.NewColumns.Set("owner", to.Tables[0].Columns[1])
+	expected.TableMigrations.Set("teams", teams)
+	expected.TableMigrations.Set("users", spec.TableMigration{NewColumnOrder: []string{"age", "email"}})
+
+	require.Equal(t, expected, migration)
+	require.False(t, isMigrationEmpty(migration))
+	require.Nil(t, compareMigrations("local", migration, "expected", expected))
+}
+
+func TestPlanMigrationNoChanges(t *testing.T) {
+	var schema spec.Schema
+	err := json.Unmarshal(defaultSchema, &schema)
+	require.NoError(t, err)
+
+	require.True(t, isMigrationEmpty(PlanMigration(schema, schema)))
+}
+
+func TestCompareMigrations(t *testing.T) {
+	a := spec.BranchMigration{
+		RemovedTables: &[]string{"a", "b"},
+		TableMigrations: &spec.BranchMigration_TableMigrations{AdditionalProperties: map[string]spec.TableMigration{
+			"users": {RemovedColumns: &[]string{"age"}},
+		}},
+	}
+	b := spec.BranchMigration{
+		RemovedTables: &[]string{"b", "c"},
+		TableMigrations: &spec.BranchMigration_TableMigrations{AdditionalProperties: map[string]spec.TableMigration{
+			"users": {RemovedColumns: &[]string{"age"}},
+		}},
+	}
+
+	require.Equal(t, []string{
+		"only the local plan deletes table a",
+		"only the server plan deletes table c",
+	}, compareMigrations("local", a, "server", b))
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xataio/cli/client/spec"
+)
+
+// destructiveChanges lists the parts of a migration that lose data: dropped
+// tables and columns, and columns whose type changes. The result is sorted,
+// and empty if the migration is safe to apply.
+func destructiveChanges(migration spec.BranchMigration) []string {
+	changes := []string{}
+	if migration.RemovedTables != nil {
+		for _, table := range *migration.RemovedTables {
+			changes = append(changes, fmt.Sprintf("drops table %s", table))
+		}
+	}
+	if migration.TableMigrations != nil {
+		for table, tableMigration := range migration.TableMigrations.AdditionalProperties {
+			if tableMigration.RemovedColumns != nil {
+				for _, column := range *tableMigration.RemovedColumns {
+					changes = append(changes, fmt.Sprintf("drops column %s.%s", table, column))
+				}
+			}
+			if tableMigration.ModifiedColumns != nil {
+				for _, column := range *tableMigration.ModifiedColumns {
+					changes = append(changes, destructiveColumnChanges(table, column.Old, column.New)...)
+				}
+			}
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+func destructiveColumnChanges(path string, old, new spec.Column) []string {
+	oldPath := path + "." + old.Name
+	if old.Type != new.Type {
+		return []string{fmt.Sprintf("changes the type of column %s from %s to %s", oldPath, old.Type, new.Type)}
+	}
+
+	switch old.Type {
+	case spec.ColumnTypeLink:
+		var oldTable, newTable string
+		if old.Link != nil {
+			oldTable = old.Link.Table
+		}
+		if new.Link != nil {
+			newTable = new.Link.Table
+		}
+		if oldTable != newTable {
+			return []string{fmt.Sprintf("changes the link of column %s from table %s to table %s", oldPath, oldTable, newTable)}
+		}
+	case spec.ColumnTypeObject:
+		// object columns are modified as a whole, so look inside them for
+		// nested columns that are dropped or change type
+		changes := []string{}
+		for _, oldColumn := range old.Columns {
+			newColumn, ok := findColumn(new.Columns, oldColumn.Name)
+			if !ok {
+				changes = append(changes, fmt.Sprintf("drops column %s.%s", oldPath, oldColumn.Name))
+				continue
+			}
+			changes = append(changes, destructiveColumnChanges(oldPath, oldColumn, newColumn)...)
+		}
+		return changes
+	}
+	return nil
+}
+
+// checkDestructivePolicy refuses a migration with destructive changes if the
+// branch policy bans them, or if the user didn't explicitly allow them when
+// deploying without confirmation.
+func checkDestructivePolicy(settings *SettingsFile, branch string, migration spec.BranchMigration, force, allowDestructive bool) error {
+	changes := destructiveChanges(migration)
+	if len(changes) == 0 {
+		return nil
+	}
+	list := strings.Join(changes, "\n  ")
+
+	if settings.Branches[branch].DenyDestructive {
+		return fmt.Errorf("The migration would lose data, which the policy of branch [%s] in %s doesn't allow:\n  %s",
+			branch, settingsFilename, list)
+	}
+	if force && !allowDestructive {
+		return fmt.Errorf("The migration would lose data:\n  %s\nUse --allow-destructive together with --force to apply it.", list)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestDestructiveChanges(t *testing.T) {
+	from := spec.Schema{
+		Tables: []spec.Table{
+			{Name: "users", Columns: []spec.Column{
+				{Name: "name", Type: spec.ColumnTypeString},
+				{Name: "age", Type: spec.ColumnTypeInt},
+				{Name: "team", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "teams"}},
+				{Name: "address", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+					{Name: "street", Type: spec.ColumnTypeString},
+					{Name: "zip", Type: spec.ColumnTypeInt},
+				}},
+				{Name: "bio", Type: spec.ColumnTypeText},
+			}},
+			{Name: "teams", Columns: []spec.Column{}},
+			{Name: "legacy", Columns: []spec.Column{}},
+		},
+	}
+	to := spec.Schema{
+		Tables: []spec.Table{
+			{Name: "users", Columns: []spec.Column{
+				{Name: "name", Type: spec.ColumnTypeString, Unique: true},
+				{Name: "age", Type: spec.ColumnTypeString},
+				{Name: "team", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "groups"}},
+				{Name: "address", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+					{Name: "zip", Type: spec.ColumnTypeString},
+				}},
+			}},
+			{Name: "teams", Columns: []spec.Column{}},
+			{Name: "groups", Columns: []spec.Column{}},
+		},
+	}
+
+	migration := PlanMigration(from, to)
+	require.Equal(t, []string{
+		"changes the link of column users.team from table teams to table groups",
+		"changes the type of column users.address.zip from int to string",
+		"changes the type of column users.age from int to string",
+		"drops column users.address.street",
+		"drops column users.bio",
+		"drops table legacy",
+	}, destructiveChanges(migration))
+
+	settings := &SettingsFile{Branches: map[string]BranchPolicy{"main": {DenyDestructive: true}}}
+	require.Error(t, checkDestructivePolicy(settings, "main", migration, false, true))
+	require.NoError(t, checkDestructivePolicy(settings, "dev", migration, false, false))
+	require.Error(t, checkDestructivePolicy(settings, "dev", migration, true, false))
+	require.NoError(t, checkDestructivePolicy(settings, "dev", migration, true, true))
+
+	safe := PlanMigration(from, from)
+	require.Empty(t, destructiveChanges(safe))
+	require.NoError(t, checkDestructivePolicy(settings, "main", safe, true, false))
+}
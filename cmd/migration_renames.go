@@ -0,0 +1,414 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/AlecAivazis/survey/v2"
+)
+
+type tableRename struct {
+	oldName string
+	newName string
+}
+
+type columnRename struct {
+	// table is the name of the table in the new schema
+	table   string
+	oldName string
+	newName string
+}
+
+// renameHints returns the renames declared with renamedFrom in the schema.
+func renameHints(schema spec.Schema) ([]tableRename, []columnRename) {
+	tables := []tableRename{}
+	columns := []columnRename{}
+	for _, table := range schema.Tables {
+		if table.RenamedFrom != "" {
+			tables = append(tables, tableRename{oldName: table.RenamedFrom, newName: table.Name})
+		}
+		for _, column := range table.Columns {
+			if column.RenamedFrom != "" {
+				columns = append(columns, columnRename{table: table.Name, oldName: column.RenamedFrom, newName: column.Name})
+			}
+		}
+	}
+	return tables, columns
+}
+
+// checkRenameHints returns an error if a column nested in an object has a
+// renamedFrom hint. Migrations change object columns as a whole, so there is
+// no way to rename a column inside one.
+func checkRenameHints(schema spec.Schema) error {
+	for _, table := range schema.Tables {
+		for _, column := range table.Columns {
+			if path, ok := nestedRenameHint(table.Name+"."+column.Name, column.Columns); ok {
+				return fmt.Errorf("Column %s has a renamedFrom hint, but only the top-level columns of a table can be renamed.", path)
+			}
+		}
+	}
+	return nil
+}
+
+// nestedRenameHint returns the path of the first column with a renamedFrom
+// hint, looking into nested objects too.
+func nestedRenameHint(parent string, columns []spec.Column) (string, bool) {
+	for _, column := range columns {
+		path := parent + "." + column.Name
+		if column.RenamedFrom != "" {
+			return path, true
+		}
+		if path, ok := nestedRenameHint(path, column.Columns); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// stripRenameHints returns a copy of the schema without renamedFrom hints,
+// which the API doesn't know about.
+func stripRenameHints(schema spec.Schema) spec.Schema {
+	tables := make([]spec.Table, 0, len(schema.Tables))
+	for _, table := range schema.Tables {
+		table.RenamedFrom = ""
+		table.Columns = stripColumnRenameHints(table.Columns)
+		tables = append(tables, table)
+	}
+	schema.Tables = tables
+	return schema
+}
+
+// stripColumnRenameHints removes the hints of nested columns too; those are
+// rejected by checkRenameHints before planning, so none are lost silently.
+func stripColumnRenameHints(columns []spec.Column) []spec.Column {
+	if columns == nil {
+		return nil
+	}
+	res := make([]spec.Column, 0, len(columns))
+	for _, column := range columns {
+		column.RenamedFrom = ""
+		column.Columns = stripColumnRenameHints(column.Columns)
+		res = append(res, column)
+	}
+	return res
+}
+
+// applyRenames rewrites a migration plan, computed by name, so that the
+// given tables and columns are renamed instead of deleted and created again.
+// Renames that don't match a removal plus an addition in the plan are
+// ignored, so hints left in the schema after a deploy are harmless.
+func applyRenames(migration *spec.BranchMigration, from, to spec.Schema, tables []tableRename, columns []columnRename) {
+	fromTables := tablesByName(stripRenameHints(from))
+	toTables := tablesByName(stripRenameHints(to))
+
+	for _, rename := range tables {
+		oldTable, oldExists := fromTables[rename.oldName]
+		newTable, newExists := toTables[rename.newName]
+		if !oldExists || !newExists ||
+			!removeString(migration.RemovedTables, rename.oldName) {
+			continue
+		}
+		if migration.NewTables != nil {
+			delete(migration.NewTables.AdditionalProperties, rename.newName)
+		}
+		if migration.RenamedTables == nil {
+			migration.RenamedTables = &[]spec.TableRename{}
+		}
+		*migration.RenamedTables = append(*migration.RenamedTables,
+			spec.TableRename{OldName: rename.oldName, NewName: rename.newName})
+
+		oldTable.Name = rename.newName
+		if tableMigration, changed := planTableMigration(oldTable, newTable); changed {
+			if migration.TableMigrations == nil {
+				migration.TableMigrations = &spec.BranchMigration_TableMigrations{}
+			}
+			migration.TableMigrations.Set(rename.newName, tableMigration)
+		}
+	}
+
+	oldTableNames := renamedTableNames(*migration)
+	for _, rename := range columns {
+		if migration.TableMigrations == nil {
+			break
+		}
+		tableMigration, ok := migration.TableMigrations.Get(rename.table)
+		if !ok || tableMigration.NewColumns == nil {
+			continue
+		}
+		newColumn, ok := tableMigration.NewColumns.Get(rename.newName)
+		if !ok {
+			continue
+		}
+		oldTableName := rename.table
+		if name, renamed := oldTableNames[rename.table]; renamed {
+			oldTableName = name
+		}
+		oldColumn, ok := findColumn(fromTables[oldTableName].Columns, rename.oldName)
+		if !ok || !removeString(tableMigration.RemovedColumns, rename.oldName) {
+			continue
+		}
+
+		delete(tableMigration.NewColumns.AdditionalProperties, rename.newName)
+		if len(tableMigration.NewColumns.AdditionalProperties) == 0 {
+			tableMigration.NewColumns = nil
+		}
+		if tableMigration.RemovedColumns != nil && len(*tableMigration.RemovedColumns) == 0 {
+			tableMigration.RemovedColumns = nil
+		}
+		if tableMigration.ModifiedColumns == nil {
+			tableMigration.ModifiedColumns = &[]spec.ColumnMigration{}
+		}
+		newColumn.RenamedFrom = ""
+		*tableMigration.ModifiedColumns = append(*tableMigration.ModifiedColumns,
+			spec.ColumnMigration{Old: oldColumn, New: newColumn})
+		migration.TableMigrations.Set(rename.table, tableMigration)
+	}
+
+	if migration.RemovedTables != nil && len(*migration.RemovedTables) == 0 {
+		migration.RemovedTables = nil
+	}
+	if migration.NewTables != nil && len(migration.NewTables.AdditionalProperties) == 0 {
+		migration.NewTables = nil
+	}
+}
+
+// detectTableRenames looks for deleted and created tables in a migration
+// plan that are likely renames: same columns, or similar names.
+func detectTableRenames(migration spec.BranchMigration, from, to spec.Schema) []tableRename {
+	fromTables := tablesByName(stripRenameHints(from))
+	toTables := tablesByName(stripRenameHints(to))
+
+	renames := []tableRename{}
+	if migration.RemovedTables == nil || migration.NewTables == nil {
+		return renames
+	}
+	used := map[string]bool{}
+	for _, oldName := range *migration.RemovedTables {
+		for _, newName := range sortedTableNames(migration.NewTables.AdditionalProperties) {
+			if used[newName] {
+				continue
+			}
+			sameColumns := columnsEqualIgnoringOrder(fromTables[oldName].Columns, toTables[newName].Columns)
+			if sameColumns || similarNames(oldName, newName) {
+				renames = append(renames, tableRename{oldName: oldName, newName: newName})
+				used[newName] = true
+				break
+			}
+		}
+	}
+	return renames
+}
+
+// detectColumnRenames looks for deleted and added columns in a migration
+// plan that are likely renames: same type, and either the same position or
+// a similar name.
+func detectColumnRenames(migration spec.BranchMigration, from, to spec.Schema) []columnRename {
+	fromTables := tablesByName(stripRenameHints(from))
+	toTables := tablesByName(stripRenameHints(to))
+
+	oldTableNames := renamedTableNames(migration)
+
+	renames := []columnRename{}
+	if migration.TableMigrations == nil {
+		return renames
+	}
+	for _, tableName := range sortedTableMigrationNames(migration.TableMigrations.AdditionalProperties) {
+		tableMigration := migration.TableMigrations.AdditionalProperties[tableName]
+		if tableMigration.RemovedColumns == nil || tableMigration.NewColumns == nil {
+			continue
+		}
+		oldTableName := tableName
+		if name, renamed := oldTableNames[tableName]; renamed {
+			oldTableName = name
+		}
+		oldColumns := fromTables[oldTableName].Columns
+		newColumns := toTables[tableName].Columns
+
+		used := map[string]bool{}
+		for _, oldName := range *tableMigration.RemovedColumns {
+			oldColumn, _ := findColumn(oldColumns, oldName)
+			for _, newName := range sortedColumnNames(tableMigration.NewColumns.AdditionalProperties) {
+				newColumn := tableMigration.NewColumns.AdditionalProperties[newName]
+				if used[newName] || newColumn.Type != oldColumn.Type {
+					continue
+				}
+				samePosition := columnIndex(oldColumns, oldName) == columnIndex(newColumns, newName)
+				if samePosition || similarNames(oldName, newName) {
+					renames = append(renames, columnRename{table: tableName, oldName: oldName, newName: newName})
+					used[newName] = true
+					break
+				}
+			}
+		}
+	}
+	return renames
+}
+
+// confirmTableRenames asks the user which of the detected table renames are real.
+func confirmTableRenames(renames []tableRename) ([]tableRename, error) {
+	confirmed := []tableRename{}
+	for _, rename := range renames {
+		var yes bool
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("Table [%s] is deleted and table [%s] is created. Was [%s] renamed to [%s]?",
+				rename.oldName, rename.newName, rename.oldName, rename.newName),
+			Default: true,
+			Help:    "Renaming keeps the data of the table, deleting it loses the data.",
+		}
+		if err := survey.AskOne(prompt, &yes); err != nil {
+			return nil, err
+		}
+		if yes {
+			confirmed = append(confirmed, rename)
+		}
+	}
+	return confirmed, nil
+}
+
+// confirmColumnRenames asks the user which of the detected column renames are real.
+func confirmColumnRenames(renames []columnRename) ([]columnRename, error) {
+	confirmed := []columnRename{}
+	for _, rename := range renames {
+		var yes bool
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("Column [%s.%s] is deleted and column [%s.%s] is added. Was it renamed?",
+				rename.table, rename.oldName, rename.table, rename.newName),
+			Default: true,
+			Help:    "Renaming keeps the data of the column, deleting it loses the data.",
+		}
+		if err := survey.AskOne(prompt, &yes); err != nil {
+			return nil, err
+		}
+		if yes {
+			confirmed = append(confirmed, rename)
+		}
+	}
+	return confirmed, nil
+}
+
+// renamedTableNames maps the new name of each renamed table to its old name.
+func renamedTableNames(migration spec.BranchMigration) map[string]string {
+	names := map[string]string{}
+	if migration.RenamedTables != nil {
+		for _, rename := range *migration.RenamedTables {
+			names[rename.NewName] = rename.OldName
+		}
+	}
+	return names
+}
+
+// hasRemovals returns true if the migration drops any table or column.
+func hasRemovals(migration spec.BranchMigration) bool {
+	if migration.RemovedTables != nil && len(*migration.RemovedTables) > 0 {
+		return true
+	}
+	if migration.TableMigrations != nil {
+		for _, tableMigration := range migration.TableMigrations.AdditionalProperties {
+			if tableMigration.RemovedColumns != nil && len(*tableMigration.RemovedColumns) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func tablesByName(schema spec.Schema) map[string]spec.Table {
+	tables := map[string]spec.Table{}
+	for _, table := range schema.Tables {
+		tables[table.Name] = table
+	}
+	return tables
+}
+
+func findColumn(columns []spec.Column, name string) (spec.Column, bool) {
+	for _, column := range columns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+	return spec.Column{}, false
+}
+
+func columnIndex(columns []spec.Column, name string) int {
+	for i, column := range columns {
+		if column.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func columnsEqualIgnoringOrder(a, b []spec.Column) bool {
+	if len(a) != len(b) || len(a) == 0 {
+		return false
+	}
+	for _, column := range a {
+		other, ok := findColumn(b, column.Name)
+		if !ok || !columnsEqual(column, other) {
+			return false
+		}
+	}
+	return true
+}
+
+// removeString removes the first occurrence of s from the list, and returns
+// true if it was found.
+func removeString(list *[]string, s string) bool {
+	if list == nil {
+		return false
+	}
+	for i, item := range *list {
+		if item == s {
+			*list = append((*list)[:i], (*list)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// similarNames returns true if two identifiers differ only by case and
+// separators, or by a small number of edits relative to their length.
+func similarNames(a, b string) bool {
+	simplify := func(s string) string {
+		return strings.NewReplacer("_", "", "-", "", "~", "").Replace(strings.ToLower(s))
+	}
+	a, b = simplify(a), simplify(b)
+	if a == b {
+		return true
+	}
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	return levenshtein(a, b)*3 <= longest
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
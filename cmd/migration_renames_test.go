@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestPlanMigrationRenameHints(t *testing.T) {
+	from := spec.Schema{
+		Tables: []spec.Table{
+			{Name: "people", Columns: []spec.Column{
+				{Name: "fullname", Type: spec.ColumnTypeString},
+				{Name: "email", Type: spec.ColumnTypeEmail},
+			}},
+		},
+	}
+	to := spec.Schema{
+		Tables: []spec.Table{
+			{Name: "users", RenamedFrom: "people", Columns: []spec.Column{
+				{Name: "full_name", RenamedFrom: "fullname", Type: spec.ColumnTypeString},
+				{Name: "email", Type: spec.ColumnTypeEmail},
+			}},
+		},
+	}
+
+	migration := PlanMigration(from, to)
+
+	require.Nil(t, migration.NewTables)
+	require.Nil(t, migration.RemovedTables)
+	require.Equal(t, &[]spec.TableRename{{OldName: "people", NewName: "users"}}, migration.RenamedTables)
+	users, ok := migration.TableMigrations.Get("users")
+	require.True(t, ok)
+	require.Nil(t, users.NewColumns)
+	require.Nil(t, users.RemovedColumns)
+	require.Equal(t, &[]spec.ColumnMigration{{
+		Old: spec.Column{Name: "fullname", Type: spec.ColumnTypeString},
+		New: spec.Column{Name: "full_name", Type: spec.ColumnTypeString},
+	}}, users.ModifiedColumns)
+	require.False(t, hasRemovals(migration))
+
+	// once deployed, the hints left in the file don't change anything
+	deployed := stripRenameHints(to)
+	require.True(t, isMigrationEmpty(PlanMigration(deployed, to)))
+}
+
+func TestDetectRenames(t *testing.T) {
+	from := spec.Schema{
+		Tables: []spec.Table{
+			{Name: "user_profile", Columns: []spec.Column{
+				{Name: "name", Type: spec.ColumnTypeString},
+				{Name: "adress", Type: spec.ColumnTypeString},
+				{Name: "age", Type: spec.ColumnTypeInt},
+				{Name: "legacy", Type: spec.ColumnTypeBool},
+			}},
+			{Name: "logs", Columns: []spec.Column{
+				{Name: "message", Type: spec.ColumnTypeText},
+			}},
+		},
+	}
+	to := spec.Schema{
+		Tables: []spec.Table{
+			{Name: "userProfile", Columns: []spec.Column{
+				{Name: "name", Type: spec.ColumnTypeString},
+				{Name: "address", Type: spec.ColumnTypeString},
+				{Name: "years", Type: spec.ColumnTypeInt},
+				{Name: "score", Type: spec.ColumnTypeFloat},
+			}},
+			{Name: "events", Columns: []spec.Column{
+				{Name: "message", Type: spec.ColumnTypeText},
+			}},
+		},
+	}
+
+	migration := PlanMigration(from, to)
+	tables := detectTableRenames(migration, from, to)
+	require.Equal(t, []tableRename{
+		{oldName: "user_profile", newName: "userProfile"},
+		{oldName: "logs", newName: "events"},
+	}, tables)
+
+	applyRenames(&migration, from, to, tables, nil)
+	columns := detectColumnRenames(migration, from, to)
+	require.Equal(t, []columnRename{
+		{table: "userProfile", oldName: "adress", newName: "address"},
+		{table: "userProfile", oldName: "age", newName: "years"},
+	}, columns)
+
+	applyRenames(&migration, from, to, nil, columns)
+	profile, ok := migration.TableMigrations.Get("userProfile")
+	require.True(t, ok)
+	require.Equal(t, &[]string{"legacy"}, profile.RemovedColumns)
+	require.Equal(t, []string{"score"}, sortedColumnNames(profile.NewColumns.AdditionalProperties))
+	require.Len(t, *profile.ModifiedColumns, 2)
+}
+
+func TestSimilarNames(t *testing.T) {
+	require.True(t, similarNames("full_name", "fullName"))
+	require.True(t, similarNames("adress", "address"))
+	require.False(t, similarNames("age", "years"))
+	require.False(t, similarNames("id", "ab"))
+}
+
+func TestCheckRenameHintsNested(t *testing.T) {
+	schema := spec.Schema{
+		Tables: []spec.Table{
+			{Name: "users", RenamedFrom: "people", Columns: []spec.Column{
+				{Name: "full_name", RenamedFrom: "fullname", Type: spec.ColumnTypeString},
+				{Name: "address", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+					{Name: "geo", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+						{Name: "latitude", RenamedFrom: "lat", Type: spec.ColumnTypeFloat},
+					}},
+				}},
+			}},
+		},
+	}
+	require.EqualError(t, checkRenameHints(schema),
+		"Column users.address.geo.latitude has a renamedFrom hint, but only the top-level columns of a table can be renamed.")
+
+	schema.Tables[0].Columns[1].Columns[0].Columns[0].RenamedFrom = ""
+	require.NoError(t, checkRenameHints(schema))
+}
+
+func TestApplyRenamesTableRenameWithoutColumnChanges(t *testing.T) {
+	from := spec.Schema{Tables: []spec.Table{
+		{Name: "people", Columns: []spec.Column{{Name: "email", Type: spec.ColumnTypeEmail}}},
+	}}
+	to := spec.Schema{Tables: []spec.Table{
+		{Name: "users", RenamedFrom: "people", Columns: []spec.Column{
+			// a hint left over from an earlier deploy
+			{Name: "email", RenamedFrom: "mail", Type: spec.ColumnTypeEmail},
+		}},
+	}}
+
+	migration := PlanMigration(from, to)
+
+	require.Nil(t, migration.RemovedTables)
+	require.Nil(t, migration.NewTables)
+	require.Nil(t, migration.TableMigrations)
+	require.Equal(t, &[]spec.TableRename{{OldName: "people", NewName: "users"}}, migration.RenamedTables)
+}
@@ -1,12 +1,10 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
-	"path"
 
 	"github.com/xataio/cli/client"
 	"github.com/xataio/cli/client/spec"
@@ -14,9 +12,7 @@ import (
 	"github.com/xataio/cli/filesystem"
 
 	"github.com/AlecAivazis/survey/v2"
-	"github.com/tidwall/pretty"
 	"github.com/urfave/cli/v2"
-	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -32,10 +28,7 @@ func PullCommand(c *cli.Context) error {
 		return err
 	}
 
-	schemaFile := path.Join(dir, "schema.json")
-	if settings.SchemaFileFormat == SettingsYAML {
-		schemaFile = path.Join(dir, "schema.yaml")
-	}
+	schemaFile := SchemaFilePath(dir, settings)
 
 	dbName, _, branch, err := getDBNameAndBranch(c)
 	if err != nil {
@@ -109,18 +102,9 @@ func PullCommand(c *cli.Context) error {
 	baseBranch := resp.JSON200
 	version := schemaVersion
 	baseBranch.Schema.FormatVersion = version
-	var file []byte
-	if settings.SchemaFileFormat == SettingsYAML {
-		file, err = yaml.Marshal(baseBranch.Schema)
-		if err != nil {
-			return err
-		}
-	} else {
-		file, err = json.MarshalIndent(baseBranch.Schema, "", " ")
-		if err != nil {
-			return err
-		}
-		file = pretty.Pretty(file)
+	file, err := marshalSchema(settings.SchemaFileFormat, baseBranch.Schema)
+	if err != nil {
+		return err
 	}
 
 	err = ioutil.WriteFile(schemaFile, file, 0644)
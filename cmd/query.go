@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	xataclient "github.com/xataio/cli/client"
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/urfave/cli/v2"
+)
+
+// compileSort compiles a list of columns, prefixed with `-` for descending
+// order, to a Xata sort expression.
+func compileSort(columns []string) (spec.SortExpression, error) {
+	sorts := []map[string]string{}
+	for _, column := range columns {
+		column = strings.TrimSpace(column)
+		direction := "asc"
+		if strings.HasPrefix(column, "-") {
+			column, direction = column[1:], "desc"
+		} else if strings.HasPrefix(column, "+") {
+			column = column[1:]
+		}
+		if column == "" {
+			return nil, fmt.Errorf("Invalid --sort: empty column name")
+		}
+		sorts = append(sorts, map[string]string{column: direction})
+	}
+	return sorts, nil
+}
+
+// buildQuery builds the request body of a query from the --where, --columns,
+// --sort and --limit flags. With --all, --limit doesn't set the page size.
+func buildQuery(c *cli.Context) (spec.QueryTableJSONRequestBody, error) {
+	body := spec.QueryTableJSONRequestBody{}
+	if where := c.String("where"); strings.TrimSpace(where) != "" {
+		filter, err := CompileFilter(where)
+		if err != nil {
+			return body, err
+		}
+		body.Filter = filter
+	}
+	if columns := c.StringSlice("columns"); len(columns) > 0 {
+		filter := spec.ColumnsFilter(columns)
+		body.Columns = &filter
+	}
+	if columns := c.StringSlice("sort"); len(columns) > 0 {
+		sort, err := compileSort(columns)
+		if err != nil {
+			return body, err
+		}
+		body.Sort = &sort
+	}
+	if c.IsSet("limit") && !c.Bool("all") {
+		limit := c.Int("limit")
+		if limit < 1 || limit > xataclient.MaxPageSize {
+			return body, fmt.Errorf("Invalid --limit %d: must be between 1 and %d", limit, xataclient.MaxPageSize)
+		}
+		body.Page = &spec.PageConfig{Size: &limit}
+	}
+	return body, nil
+}
+
+func checkQueryResponse(resp *spec.QueryTableResponse) error {
+	if resp.JSON401 != nil {
+		return ErrorUnauthorized{message: resp.JSON401.Message}
+	}
+	if resp.JSON400 != nil {
+		return fmt.Errorf("Error querying table: %s", resp.JSON400.Message)
+	}
+	if resp.JSON404 != nil {
+		return fmt.Errorf("Error querying table: %s", resp.JSON404.Message)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return fmt.Errorf("Error querying table: %s", resp.Status())
+	}
+	if resp.JSON200 == nil {
+		return fmt.Errorf("Error querying table: 200 OK unexpected response body")
+	}
+	return nil
+}
+
+// QueryCommand queries the records of a table.
+func QueryCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("Expected exactly one argument: the table name")
+	}
+	table := c.Args().First()
+
+	body, err := buildQuery(c)
+	if err != nil {
+		return err
+	}
+
+	dbName, _, branch, err := getDBNameAndBranch(c)
+	if err != nil {
+		return err
+	}
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+	dbBranch := spec.DBBranchNameParam(fmt.Sprintf("%s:%s", dbName, branch))
+
+	if c.Bool("all") {
+		it := xataclient.NewRecordIterator(client, dbBranch, spec.TableNameParam(table), body, c.Int("page-size"))
+		return writeRecordsNDJSON(c.Context, os.Stdout, it, c.Int("limit"))
+	}
+
+	resp, err := client.QueryTableWithResponse(c.Context, dbBranch, spec.TableNameParam(table), body)
+	if err != nil {
+		return fmt.Errorf("Error querying table: %w", err)
+	}
+	if err := checkQueryResponse(resp); err != nil {
+		return err
+	}
+
+	records := resp.JSON200.Records
+	if c.Bool("json") {
+		out, err := json.Marshal(records)
+		if err != nil {
+			return err
+		}
+		return printJSON(c, out)
+	}
+	printRecords(records, c.StringSlice("columns"))
+	return nil
+}
+
+// writeRecordsNDJSON writes the records of the iterator as they come, one
+// JSON document per line. A limit greater than 0 stops after that many
+// records.
+func writeRecordsNDJSON(ctx context.Context, out io.Writer, it *xataclient.RecordIterator, limit int) error {
+	w := bufio.NewWriter(out)
+	encoder := json.NewEncoder(w)
+	for count := 0; (limit <= 0 || count < limit) && it.Next(ctx); count++ {
+		if err := encoder.Encode(it.Record()); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return iteratorError(it.Err())
+}
+
+// iteratorError returns the error of a record iterator, if any, as the
+// errors of the commands.
+func iteratorError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var queryErr *xataclient.QueryError
+	if errors.As(err, &queryErr) && queryErr.StatusCode == http.StatusUnauthorized {
+		return ErrorUnauthorized{message: queryErr.Message}
+	}
+	return fmt.Errorf("Error querying table: %w", err)
+}
+
+// printRecords prints records as a table with the id and the given columns,
+// or all the columns of the records, sorted by name, if none are given.
+func printRecords(records []spec.Record, columns []string) {
+	if len(records) == 0 {
+		fmt.Println("No records found.")
+		return
+	}
+	if len(columns) == 0 {
+		columns = recordColumns(records)
+	}
+
+	headers := append([]string{"id"}, columns...)
+	rows := make([][]interface{}, 0, len(records))
+	for _, record := range records {
+		row := []interface{}{string(record.Id)}
+		for _, column := range columns {
+			row = append(row, recordCellValue(recordValue(record.AdditionalProperties, column)))
+		}
+		rows = append(rows, row)
+	}
+	printTable(headers, rows)
+}
+
+func recordColumns(records []spec.Record) []string {
+	seen := map[string]bool{}
+	columns := []string{}
+	for _, record := range records {
+		for name := range record.AdditionalProperties {
+			if !seen[name] {
+				seen[name] = true
+				columns = append(columns, name)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// recordValue returns the value at a dotted path, like address.city, in the
+// columns of a record.
+func recordValue(values map[string]interface{}, path string) interface{} {
+	if value, ok := values[path]; ok {
+		return value
+	}
+	parts := strings.SplitN(path, ".", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	nested, ok := values[parts[0]].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return recordValue(nested, parts[1])
+}
+
+func recordCellValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		out, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(out)
+	}
+}
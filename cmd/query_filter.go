@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/xataio/cli/client/spec"
+)
+
+// CompileFilter compiles the --where syntax of `xata query` to a Xata filter
+// expression. Examples:
+//
+//	age > 30 and team.name = "core"
+//	not (email endsWith "@example.com") or role in ("admin", "owner")
+//	address exists and name like "Jo*"
+//
+// Comparisons are `=`, `!=`, `>`, `>=`, `<`, `<=` and the word operators
+// contains, startsWith, endsWith, like (a pattern with * and ?) and includes
+// (for multiple columns). `and` binds tighter than `or`, and parentheses
+// group. Values are double or single quoted strings, numbers, true or false.
+func CompileFilter(where string) (*spec.FilterExpression, error) {
+	tokens, err := lexFilter(where)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{src: where, tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != filterEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok.describe())
+	}
+	return expr, nil
+}
+
+// FilterSyntaxError is a syntax error in a --where filter. Pos is the 0 based
+// byte offset of the error in the source.
+type FilterSyntaxError struct {
+	Source  string
+	Pos     int
+	Message string
+}
+
+func (e *FilterSyntaxError) Error() string {
+	return fmt.Sprintf("invalid filter at column %d: %s\n  %s\n  %s^", e.Pos+1, e.Message, e.Source, strings.Repeat(" ", e.Pos))
+}
+
+type filterTokenKind int
+
+const (
+	filterIdent filterTokenKind = iota
+	filterString
+	filterNumber
+	filterOperator
+	filterLParen
+	filterRParen
+	filterComma
+	filterEOF
+)
+
+type filterToken struct {
+	kind  filterTokenKind
+	value string
+	pos   int
+}
+
+func (t filterToken) describe() string {
+	switch t.kind {
+	case filterString:
+		return fmt.Sprintf("string %q", t.value)
+	case filterEOF:
+		return "end of filter"
+	default:
+		return fmt.Sprintf("`%s`", t.value)
+	}
+}
+
+// isKeyword returns true if the token is the given word, ignoring case.
+func (t filterToken) isKeyword(word string) bool {
+	return t.kind == filterIdent && strings.EqualFold(t.value, word)
+}
+
+func lexFilter(src string) ([]filterToken, error) {
+	tokens := []filterToken{}
+	errorAt := func(pos int, format string, args ...interface{}) error {
+		return &FilterSyntaxError{Source: src, Pos: pos, Message: fmt.Sprintf(format, args...)}
+	}
+
+	runes := []rune(src)
+	// byte offsets of each rune, for error positions
+	offsets := make([]int, len(runes)+1)
+	offset := 0
+	for i, r := range runes {
+		offsets[i] = offset
+		offset += len(string(r))
+	}
+	offsets[len(runes)] = offset
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{filterLParen, "(", offsets[start]})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{filterRParen, ")", offsets[start]})
+			i++
+		case r == ',':
+			tokens = append(tokens, filterToken{filterComma, ",", offsets[start]})
+			i++
+		case r == '=' || r == '!' || r == '<' || r == '>':
+			op := string(r)
+			i++
+			if i < len(runes) && runes[i] == '=' {
+				op += "="
+				i++
+			}
+			if op == "!" {
+				return nil, errorAt(offsets[start], "expected `!=`")
+			}
+			if op == "==" {
+				op = "="
+			}
+			tokens = append(tokens, filterToken{filterOperator, op, offsets[start]})
+		case r == '"' || r == '\'':
+			var value strings.Builder
+			i++
+			closed := false
+			for i < len(runes) {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					value.WriteRune(runes[i+1])
+					i += 2
+					continue
+				}
+				if runes[i] == r {
+					closed = true
+					i++
+					break
+				}
+				value.WriteRune(runes[i])
+				i++
+			}
+			if !closed {
+				return nil, errorAt(offsets[start], "unterminated string")
+			}
+			tokens = append(tokens, filterToken{filterString, value.String(), offsets[start]})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E') {
+				i++
+			}
+			value := string(runes[start:i])
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, errorAt(offsets[start], "invalid number %s", value)
+			}
+			tokens = append(tokens, filterToken{filterNumber, value, offsets[start]})
+		case unicode.IsLetter(r) || r == '_' || r == '$':
+			// column names may contain `-` and `~` too, but not start with
+			// them, which keeps `-1` a number
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) ||
+				runes[i] == '_' || runes[i] == '.' || runes[i] == '$' || runes[i] == '-' || runes[i] == '~') {
+				i++
+			}
+			tokens = append(tokens, filterToken{filterIdent, string(runes[start:i]), offsets[start]})
+		default:
+			return nil, errorAt(offsets[start], "unexpected character %q", r)
+		}
+	}
+	tokens = append(tokens, filterToken{filterEOF, "", offset})
+	return tokens, nil
+}
+
+// wordOperators maps the word operators to the Xata filter operators.
+var wordOperators = map[string]string{
+	"contains":   "$contains",
+	"startswith": "$startsWith",
+	"endswith":   "$endsWith",
+	"like":       "$pattern",
+	"includes":   "$includes",
+}
+
+var symbolOperators = map[string]string{
+	"!=": "$isNot",
+	">":  "$gt",
+	">=": "$ge",
+	"<":  "$lt",
+	"<=": "$le",
+}
+
+type filterParser struct {
+	src    string
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != filterEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *filterParser) errorf(tok filterToken, format string, args ...interface{}) error {
+	return &FilterSyntaxError{Source: p.src, Pos: tok.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *filterParser) parseOr() (*spec.FilterExpression, error) {
+	exprs := []spec.FilterExpression{}
+	for {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, *expr)
+		if !p.peek().isKeyword("or") {
+			break
+		}
+		p.next()
+	}
+	if len(exprs) == 1 {
+		return &exprs[0], nil
+	}
+	var list spec.FilterList = exprs
+	return &spec.FilterExpression{Any: &list}, nil
+}
+
+func (p *filterParser) parseAnd() (*spec.FilterExpression, error) {
+	exprs := []spec.FilterExpression{}
+	for {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, *expr)
+		if !p.peek().isKeyword("and") {
+			break
+		}
+		p.next()
+	}
+	if len(exprs) == 1 {
+		return &exprs[0], nil
+	}
+	var list spec.FilterList = exprs
+	return &spec.FilterExpression{All: &list}, nil
+}
+
+func (p *filterParser) parseNot() (*spec.FilterExpression, error) {
+	if !p.peek().isKeyword("not") {
+		return p.parsePrimary()
+	}
+	p.next()
+	expr, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	var list spec.FilterList = *expr
+	return &spec.FilterExpression{Not: &list}, nil
+}
+
+func (p *filterParser) parsePrimary() (*spec.FilterExpression, error) {
+	tok := p.next()
+	if tok.kind == filterLParen {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != filterRParen {
+			return nil, p.errorf(closing, "expected `)` to close the `(` at column %d, found %s", tok.pos+1, closing.describe())
+		}
+		return expr, nil
+	}
+	if tok.kind != filterIdent {
+		return nil, p.errorf(tok, "expected a column name, found %s", tok.describe())
+	}
+	column := tok.value
+
+	op := p.next()
+	var predicate interface{}
+	switch {
+	case op.isKeyword("exists"):
+		return &spec.FilterExpression{Exists: &column}, nil
+	case op.isKeyword("in"):
+		values, err := p.parseValueList(op)
+		if err != nil {
+			return nil, err
+		}
+		predicate = map[string]interface{}{"$any": values}
+	case op.kind == filterOperator && op.value == "=":
+		value, err := p.parseValue(op)
+		if err != nil {
+			return nil, err
+		}
+		predicate = value
+	case op.kind == filterOperator:
+		value, err := p.parseValue(op)
+		if err != nil {
+			return nil, err
+		}
+		predicate = map[string]interface{}{symbolOperators[op.value]: value}
+	case op.kind == filterIdent && wordOperators[strings.ToLower(op.value)] != "":
+		value, err := p.parseValue(op)
+		if err != nil {
+			return nil, err
+		}
+		predicate = map[string]interface{}{wordOperators[strings.ToLower(op.value)]: value}
+	default:
+		return nil, p.errorf(op, "expected an operator after column %s, found %s", column, op.describe())
+	}
+
+	expr := &spec.FilterExpression{}
+	expr.Set(column, predicate)
+	return expr, nil
+}
+
+func (p *filterParser) parseValue(op filterToken) (interface{}, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == filterString:
+		return tok.value, nil
+	case tok.kind == filterNumber:
+		if i, err := strconv.ParseInt(tok.value, 10, 64); err == nil {
+			return i, nil
+		}
+		f, _ := strconv.ParseFloat(tok.value, 64)
+		return f, nil
+	case tok.isKeyword("true"):
+		return true, nil
+	case tok.isKeyword("false"):
+		return false, nil
+	case tok.kind == filterIdent:
+		return nil, p.errorf(tok, "expected a value after %s, found %s (quote strings)", op.describe(), tok.describe())
+	}
+	return nil, p.errorf(tok, "expected a value after %s, found %s", op.describe(), tok.describe())
+}
+
+func (p *filterParser) parseValueList(op filterToken) ([]interface{}, error) {
+	if tok := p.next(); tok.kind != filterLParen {
+		return nil, p.errorf(tok, "expected `(` after `in`, found %s", tok.describe())
+	}
+	values := []interface{}{}
+	for {
+		value, err := p.parseValue(op)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		tok := p.next()
+		if tok.kind == filterRParen {
+			return values, nil
+		}
+		if tok.kind != filterComma {
+			return nil, p.errorf(tok, "expected `,` or `)` in the list of values, found %s", tok.describe())
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		where string
+		want  string
+	}{
+		{
+			name:  "equality",
+			where: `name = "Alice"`,
+			want:  `{"name":"Alice"}`,
+		},
+		{
+			name:  "and with nested column",
+			where: `age > 30 and team.name = "core"`,
+			want:  `{"$all":[{"age":{"$gt":30}},{"team.name":"core"}]}`,
+		},
+		{
+			name:  "and binds tighter than or",
+			where: `a = 1 or b = 2 and c = 3`,
+			want:  `{"$any":[{"a":1},{"$all":[{"b":2},{"c":3}]}]}`,
+		},
+		{
+			name:  "parentheses",
+			where: `(a = 1 or b = 2) and c = 3`,
+			want:  `{"$all":[{"$any":[{"a":1},{"b":2}]},{"c":3}]}`,
+		},
+		{
+			name:  "not and exists",
+			where: `not email endsWith '@example.com' AND address exists`,
+			want:  `{"$all":[{"$not":{"email":{"$endsWith":"@example.com"}}},{"$exists":"address"}]}`,
+		},
+		{
+			name:  "operators and values",
+			where: `score <= -1.5 and active != false and name like "Jo*" and role in ("admin", "owner")`,
+			want: `{"$all":[{"score":{"$le":-1.5}},{"active":{"$isNot":false}},` +
+				`{"name":{"$pattern":"Jo*"}},{"role":{"$any":["admin","owner"]}}]}`,
+		},
+		{
+			name:  "dashes and tildes in column names",
+			where: `first-name = "Al" and address.zip~code>-1`,
+			want:  `{"$all":[{"first-name":"Al"},{"address.zip~code":{"$gt":-1}}]}`,
+		},
+		{
+			name:  "escaped quotes",
+			where: `title contains "say \"hi\""`,
+			want:  `{"title":{"$contains":"say \"hi\""}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := CompileFilter(test.where)
+			require.NoError(t, err)
+			out, err := json.Marshal(filter)
+			require.NoError(t, err)
+			require.JSONEq(t, test.want, string(out))
+		})
+	}
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	tests := []struct {
+		where string
+		pos   int
+		msg   string
+	}{
+		{`age >`, 5, "expected a value after `>`, found end of filter"},
+		{`name = Alice`, 7, "expected a value after `=`, found `Alice` (quote strings)"},
+		{`name "Alice"`, 5, "expected an operator after column name"},
+		{`(a = 1 or b = 2`, 15, "expected `)` to close the `(` at column 1"},
+		{`a = 1 b = 2`, 6, "unexpected `b`"},
+		{`name = "Alice`, 7, "unterminated string"},
+		{`a ! 1`, 2, "expected `!=`"},
+		{`a = 1 & b = 2`, 6, "unexpected character '&'"},
+		{`role in "admin"`, 8, "expected `(` after `in`"},
+	}
+	for _, test := range tests {
+		t.Run(test.where, func(t *testing.T) {
+			_, err := CompileFilter(test.where)
+			require.Error(t, err)
+			syntaxErr, ok := err.(*FilterSyntaxError)
+			require.True(t, ok)
+			require.Equal(t, test.pos, syntaxErr.Pos)
+			require.Contains(t, syntaxErr.Message, test.msg)
+		})
+	}
+}
+
+func TestCompileSort(t *testing.T) {
+	sort, err := compileSort([]string{"-createdAt", "name"})
+	require.NoError(t, err)
+	out, err := json.Marshal(sort)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"createdAt":"desc"},{"name":"asc"}]`, string(out))
+
+	_, err = compileSort([]string{"-"})
+	require.Error(t, err)
+}
+
+func TestRecordValue(t *testing.T) {
+	values := map[string]interface{}{
+		"name":    "Alice",
+		"address": map[string]interface{}{"city": "Berlin"},
+	}
+	require.Equal(t, "Alice", recordValue(values, "name"))
+	require.Equal(t, "Berlin", recordValue(values, "address.city"))
+	require.Nil(t, recordValue(values, "address.zip"))
+	require.Equal(t, `{"city":"Berlin"}`, recordCellValue(values["address"]))
+	require.Equal(t, "", recordCellValue(nil))
+}
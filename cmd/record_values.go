@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xataio/cli/client/spec"
+)
+
+// fieldAssignment is a `--set path=value` flag, with the path split on dots.
+type fieldAssignment struct {
+	path  []string
+	value string
+}
+
+// parseAssignments parses the values of --set flags. As the flag values are
+// split on commas, a value without `=` is the continuation of the previous
+// one, so that `--set tags=a,b` works.
+func parseAssignments(values []string) ([]fieldAssignment, error) {
+	assignments := []fieldAssignment{}
+	for _, value := range values {
+		i := strings.Index(value, "=")
+		if i < 0 {
+			if len(assignments) == 0 {
+				return nil, fmt.Errorf("Invalid --set %q: expected column=value", value)
+			}
+			assignments[len(assignments)-1].value += "," + value
+			continue
+		}
+		path := strings.TrimSpace(value[:i])
+		if path == "" {
+			return nil, fmt.Errorf("Invalid --set %q: expected column=value", value)
+		}
+		assignments = append(assignments, fieldAssignment{path: strings.Split(path, "."), value: value[i+1:]})
+	}
+	return assignments, nil
+}
+
+// buildRecordBody merges the --set assignments into a record read from a
+// file, if any, and coerces all the values to the types of the table
+// columns. The id and xata fields of the record are dropped.
+func buildRecordBody(table spec.Table, record map[string]interface{}, assignments []fieldAssignment) (map[string]interface{}, error) {
+	body := map[string]interface{}{}
+	for name, value := range record {
+		if name == "id" || name == "xata" {
+			continue
+		}
+		body[name] = value
+	}
+	body, err := coerceRecord(table.Name, table.Columns, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, assignment := range assignments {
+		columns := table.Columns
+		values := body
+		path := table.Name
+		for i, name := range assignment.path {
+			path += "." + name
+			column, ok := findColumn(columns, name)
+			if !ok {
+				return nil, fmt.Errorf("Unknown column %s", path)
+			}
+			if i == len(assignment.path)-1 {
+				value, err := coerceValue(path, column, assignment.value)
+				if err != nil {
+					return nil, err
+				}
+				values[name] = value
+				break
+			}
+			if column.Type != spec.ColumnTypeObject {
+				return nil, fmt.Errorf("Column %s is a %s, not an object", path, column.Type)
+			}
+			nested, ok := values[name].(map[string]interface{})
+			if !ok {
+				nested = map[string]interface{}{}
+				values[name] = nested
+			}
+			columns, values = column.Columns, nested
+		}
+	}
+	return body, nil
+}
+
+// coerceRecord coerces the values of a record to the types of the columns.
+func coerceRecord(path string, columns []spec.Column, record map[string]interface{}) (map[string]interface{}, error) {
+	coerced := map[string]interface{}{}
+	for name, value := range record {
+		column, ok := findColumn(columns, name)
+		if !ok {
+			return nil, fmt.Errorf("Unknown column %s.%s", path, name)
+		}
+		value, err := coerceValue(path+"."+name, column, value)
+		if err != nil {
+			return nil, err
+		}
+		coerced[name] = value
+	}
+	return coerced, nil
+}
+
+// coerceValue converts a value to the type of the column. Strings, as given
+// on the command line, are parsed: `42` for int columns, `true` for bool,
+// `a,b` or a JSON array for multiple and a JSON object for objects. nil is
+// kept, to unset a column.
+func coerceValue(path string, column spec.Column, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	invalid := func() error {
+		return fmt.Errorf("Invalid value %s for column %s of type %s", recordCellValue(value), path, column.Type)
+	}
+	s, isString := value.(string)
+
+	switch column.Type {
+	case spec.ColumnTypeString, spec.ColumnTypeText, spec.ColumnTypeEmail:
+		if isString {
+			return s, nil
+		}
+		switch value.(type) {
+		case float64, bool:
+			return recordCellValue(value), nil
+		}
+		return nil, invalid()
+	case spec.ColumnTypeLink:
+		if isString {
+			return s, nil
+		}
+		// a linked record, as returned by the API
+		if linked, ok := value.(map[string]interface{}); ok {
+			if id, ok := linked["id"].(string); ok {
+				return id, nil
+			}
+		}
+		return nil, invalid()
+	case spec.ColumnTypeInt:
+		if isString {
+			i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return nil, invalid()
+			}
+			return i, nil
+		}
+		if f, ok := value.(float64); ok && f == math.Trunc(f) {
+			return int64(f), nil
+		}
+		return nil, invalid()
+	case spec.ColumnTypeFloat:
+		if isString {
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return nil, invalid()
+			}
+			return f, nil
+		}
+		if f, ok := value.(float64); ok {
+			return f, nil
+		}
+		return nil, invalid()
+	case spec.ColumnTypeBool:
+		if isString {
+			b, err := strconv.ParseBool(strings.TrimSpace(s))
+			if err != nil {
+				return nil, invalid()
+			}
+			return b, nil
+		}
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return nil, invalid()
+	case spec.ColumnTypeMultiple:
+		if isString {
+			if strings.HasPrefix(strings.TrimSpace(s), "[") {
+				var list []interface{}
+				if err := json.Unmarshal([]byte(s), &list); err != nil {
+					return nil, invalid()
+				}
+				value = list
+			} else if s == "" {
+				return []string{}, nil
+			} else {
+				return strings.Split(s, ","), nil
+			}
+		}
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil, invalid()
+		}
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			item, ok := item.(string)
+			if !ok {
+				return nil, invalid()
+			}
+			values = append(values, item)
+		}
+		return values, nil
+	case spec.ColumnTypeObject:
+		if isString {
+			var object map[string]interface{}
+			if err := json.Unmarshal([]byte(s), &object); err != nil {
+				return nil, invalid()
+			}
+			value = object
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, invalid()
+		}
+		return coerceRecord(path, column.Columns, object)
+	}
+	return value, nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+var usersTable = spec.Table{
+	Name: "users",
+	Columns: []spec.Column{
+		{Name: "name", Type: spec.ColumnTypeString},
+		{Name: "age", Type: spec.ColumnTypeInt},
+		{Name: "score", Type: spec.ColumnTypeFloat},
+		{Name: "active", Type: spec.ColumnTypeBool},
+		{Name: "tags", Type: spec.ColumnTypeMultiple},
+		{Name: "team", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "teams"}},
+		{Name: "address", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+			{Name: "street", Type: spec.ColumnTypeString},
+			{Name: "zipcode", Type: spec.ColumnTypeInt},
+		}},
+	},
+}
+
+func TestParseAssignments(t *testing.T) {
+	assignments, err := parseAssignments([]string{"name=foo", "tags=a", "b", "address.zipcode=123", "note=x=y"})
+	require.NoError(t, err)
+	require.Equal(t, []fieldAssignment{
+		{path: []string{"name"}, value: "foo"},
+		{path: []string{"tags"}, value: "a,b"},
+		{path: []string{"address", "zipcode"}, value: "123"},
+		{path: []string{"note"}, value: "x=y"},
+	}, assignments)
+
+	_, err = parseAssignments([]string{"foo"})
+	require.Error(t, err)
+	_, err = parseAssignments([]string{"=foo"})
+	require.Error(t, err)
+}
+
+func TestBuildRecordBody(t *testing.T) {
+	assignments, err := parseAssignments([]string{
+		"name=Alice", "age=42", "score=1.5", "active=true", "tags=a", "b",
+		"address.zipcode=123", "team=rec_1",
+	})
+	require.NoError(t, err)
+	body, err := buildRecordBody(usersTable, map[string]interface{}{
+		"id":      "rec_x",
+		"xata":    map[string]interface{}{"version": float64(3)},
+		"name":    "Bob",
+		"address": map[string]interface{}{"street": "Main St"},
+	}, assignments)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"name":    "Alice",
+		"age":     int64(42),
+		"score":   1.5,
+		"active":  true,
+		"tags":    []string{"a", "b"},
+		"team":    "rec_1",
+		"address": map[string]interface{}{"street": "Main St", "zipcode": int64(123)},
+	}, body)
+
+	// values from a JSON file
+	body, err = buildRecordBody(usersTable, map[string]interface{}{
+		"age":  float64(30),
+		"tags": []interface{}{"x"},
+		"team": map[string]interface{}{"id": "rec_2", "name": "core"},
+		"name": nil,
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"age":  int64(30),
+		"tags": []string{"x"},
+		"team": "rec_2",
+		"name": nil,
+	}, body)
+}
+
+func TestBuildRecordBodyErrors(t *testing.T) {
+	tests := []struct {
+		set []string
+		err string
+	}{
+		{[]string{"age=old"}, "Invalid value old for column users.age of type int"},
+		{[]string{"active=maybe"}, "Invalid value maybe for column users.active of type bool"},
+		{[]string{"nickname=Al"}, "Unknown column users.nickname"},
+		{[]string{"address.city=Berlin"}, "Unknown column users.address.city"},
+		{[]string{"name.first=Al"}, "Column users.name is a string, not an object"},
+		{[]string{"address={"}, "Invalid value { for column users.address of type object"},
+	}
+	for _, test := range tests {
+		assignments, err := parseAssignments(test.set)
+		require.NoError(t, err)
+		_, err = buildRecordBody(usersTable, nil, assignments)
+		require.EqualError(t, err, test.err)
+	}
+
+	_, err := buildRecordBody(usersTable, map[string]interface{}{"age": 1.5}, nil)
+	require.EqualError(t, err, "Invalid value 1.5 for column users.age of type int")
+}
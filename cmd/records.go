@@ -0,0 +1,362 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/urfave/cli/v2"
+)
+
+func GetRecordsSubcommands() []*cli.Command {
+	bodyFlags := func(flags ...cli.Flag) []cli.Flag {
+		return append([]cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "set",
+				Usage: "Set a column, as `COLUMN=VALUE`. Use dots for the columns of objects, e.g. address.zipcode=123. Can be specified multiple times.",
+			},
+			&cli.StringFlag{
+				Name:  "file",
+				Usage: "Read the record from a JSON `FILE`, or from stdin with -. --set values are applied on top.",
+			},
+		}, flags...)
+	}
+	ifVersion := &cli.IntFlag{
+		Name:  "if-version",
+		Usage: "Only write the record if its current version is `VERSION`.",
+	}
+
+	return []*cli.Command{
+		{
+			Name:      "get",
+			Usage:     "Get a record by ID",
+			ArgsUsage: "<table> <id>",
+			Action:    getRecord,
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "columns",
+					Usage: "Comma separated list of columns to return (default: all).",
+				},
+			},
+		},
+		{
+			Name:      "insert",
+			Usage:     "Insert a new record",
+			ArgsUsage: "<table>",
+			Action:    insertRecord,
+			Flags: bodyFlags(&cli.StringFlag{
+				Name:  "id",
+				Usage: "The `ID` of the new record (default: generated).",
+			}),
+		},
+		{
+			Name:      "update",
+			Usage:     "Update some columns of an existing record",
+			ArgsUsage: "<table> <id>",
+			Action:    updateRecord,
+			Flags:     bodyFlags(ifVersion),
+		},
+		{
+			Name:      "upsert",
+			Usage:     "Replace a record, or insert it if it doesn't exist",
+			ArgsUsage: "<table> <id>",
+			Action:    upsertRecord,
+			Flags:     bodyFlags(ifVersion),
+		},
+		{
+			Name:      "edit",
+			Usage:     "Edit a record in $EDITOR and update the changed columns",
+			ArgsUsage: "<table> <id>",
+			Action:    editRecord,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "format",
+					Usage: "Edit the record as yaml or json.",
+					Value: "yaml",
+				},
+			},
+		},
+		{
+			Name:      "delete",
+			Usage:     "Delete a record",
+			ArgsUsage: "<table> <id>",
+			Action:    deleteRecord,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:    "force",
+					Aliases: []string{"f"},
+					Usage:   "Delete without asking for confirmation.",
+				},
+			},
+		},
+	}
+}
+
+// recordArgs returns the table and, if withID, the record ID arguments.
+func recordArgs(c *cli.Context, withID bool) (spec.TableNameParam, spec.RecordIDParam, error) {
+	if withID && c.NArg() != 2 {
+		return "", "", fmt.Errorf("Expected exactly two arguments: the table name and the record ID")
+	}
+	if !withID && c.NArg() != 1 {
+		return "", "", fmt.Errorf("Expected exactly one argument: the table name")
+	}
+	return spec.TableNameParam(c.Args().Get(0)), spec.RecordIDParam(c.Args().Get(1)), nil
+}
+
+func getDBBranchParam(c *cli.Context) (spec.DBBranchNameParam, string, string, error) {
+	dbName, _, branch, err := getDBNameAndBranch(c)
+	if err != nil {
+		return "", "", "", err
+	}
+	return spec.DBBranchNameParam(fmt.Sprintf("%s:%s", dbName, branch)), dbName, branch, nil
+}
+
+// readRecordBody builds the body of a write from the --file and --set flags,
+// coerced to the types of the table columns in the branch schema.
+func readRecordBody(c *cli.Context, dbName, branch string, table spec.TableNameParam) (map[string]interface{}, error) {
+	assignments, err := parseAssignments(c.StringSlice("set"))
+	if err != nil {
+		return nil, err
+	}
+	record := map[string]interface{}{}
+	if filename := c.String("file"); filename != "" {
+		var bytes []byte
+		if filename == "-" {
+			bytes, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			bytes, err = ioutil.ReadFile(filename)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading record: %w", err)
+		}
+		if err := json.Unmarshal(bytes, &record); err != nil {
+			return nil, fmt.Errorf("parsing record from %s: %w", filename, err)
+		}
+	} else if len(assignments) == 0 {
+		return nil, fmt.Errorf("Nothing to write: use --set COLUMN=VALUE or --file")
+	}
+
+	details, err := getBranchDetails(c, dbName, branch)
+	if err != nil {
+		return nil, err
+	}
+	schemaTable, ok := tablesByName(details.Schema)[string(table)]
+	if !ok {
+		return nil, fmt.Errorf("Table [%s] doesn't exist in branch [%s:%s]", table, dbName, branch)
+	}
+	return buildRecordBody(schemaTable, record, assignments)
+}
+
+func getRecord(c *cli.Context) error {
+	table, id, err := recordArgs(c, true)
+	if err != nil {
+		return err
+	}
+	dbBranch, _, _, err := getDBBranchParam(c)
+	if err != nil {
+		return err
+	}
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+
+	body := spec.GetRecordJSONRequestBody{}
+	if columns := c.StringSlice("columns"); len(columns) > 0 {
+		filter := spec.ColumnsFilter(columns)
+		body.Columns = &filter
+	}
+	resp, err := client.GetRecordWithResponse(c.Context, dbBranch, table, id, body)
+	if err != nil {
+		return fmt.Errorf("Error getting record: %w", err)
+	}
+	return printResponse(c, resp, resp.Body, nil, func() error {
+		if resp.JSON200 == nil {
+			return fmt.Errorf("Unexpected server response %s", resp.Status())
+		}
+		printRecord(*resp.JSON200)
+		return nil
+	})
+}
+
+// printRecord prints one line per column of the record, with the columns of
+// objects flattened.
+func printRecord(record spec.Record) {
+	flat := map[string]interface{}{}
+	flattenRecord("", record.AdditionalProperties, flat)
+	paths := make([]string, 0, len(flat))
+	for path := range flat {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	rows := [][]interface{}{{"id", string(record.Id)}, {"xata.version", record.Xata.Version}}
+	for _, path := range paths {
+		rows = append(rows, []interface{}{path, recordCellValue(flat[path])})
+	}
+	printTable([]string{"Column", "Value"}, rows)
+}
+
+func insertRecord(c *cli.Context) error {
+	table, _, err := recordArgs(c, false)
+	if err != nil {
+		return err
+	}
+	dbBranch, dbName, branch, err := getDBBranchParam(c)
+	if err != nil {
+		return err
+	}
+	body, err := readRecordBody(c, dbName, branch, table)
+	if err != nil {
+		return err
+	}
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+
+	if id := c.String("id"); id != "" {
+		createOnly := true
+		resp, err := client.InsertRecordWithIDWithResponse(c.Context, dbBranch, table, spec.RecordIDParam(id),
+			&spec.InsertRecordWithIDParams{CreateOnly: &createOnly}, body)
+		if err != nil {
+			return fmt.Errorf("Error inserting record: %w", err)
+		}
+		return printResponse(c, resp, resp.Body, nil, func() error {
+			if resp.JSON201 == nil {
+				return fmt.Errorf("Unexpected server response %s", resp.Status())
+			}
+			fmt.Printf("Record [%s] inserted (version %d)\n", resp.JSON201.Id, resp.JSON201.Xata.Version)
+			return nil
+		})
+	}
+
+	resp, err := client.InsertRecordWithResponse(c.Context, dbBranch, table, body)
+	if err != nil {
+		return fmt.Errorf("Error inserting record: %w", err)
+	}
+	return printResponse(c, resp, resp.Body, nil, func() error {
+		if resp.JSON201 == nil {
+			return fmt.Errorf("Unexpected server response %s", resp.Status())
+		}
+		fmt.Printf("Record [%s] inserted (version %d)\n", resp.JSON201.Id, resp.JSON201.Xata.Version)
+		return nil
+	})
+}
+
+func ifVersionParam(c *cli.Context) *int {
+	if !c.IsSet("if-version") {
+		return nil
+	}
+	version := c.Int("if-version")
+	return &version
+}
+
+func updateRecord(c *cli.Context) error {
+	table, id, err := recordArgs(c, true)
+	if err != nil {
+		return err
+	}
+	dbBranch, dbName, branch, err := getDBBranchParam(c)
+	if err != nil {
+		return err
+	}
+	body, err := readRecordBody(c, dbName, branch, table)
+	if err != nil {
+		return err
+	}
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.UpdateRecordWithIDWithResponse(c.Context, dbBranch, table, id,
+		&spec.UpdateRecordWithIDParams{IfVersion: ifVersionParam(c)}, body)
+	if err != nil {
+		return fmt.Errorf("Error updating record: %w", err)
+	}
+	return printResponse(c, resp, resp.Body, nil, func() error {
+		if resp.JSON200 == nil {
+			return fmt.Errorf("Unexpected server response %s", resp.Status())
+		}
+		fmt.Printf("Record [%s] updated (version %d)\n", resp.JSON200.Id, resp.JSON200.Xata.Version)
+		return nil
+	})
+}
+
+func upsertRecord(c *cli.Context) error {
+	table, id, err := recordArgs(c, true)
+	if err != nil {
+		return err
+	}
+	dbBranch, dbName, branch, err := getDBBranchParam(c)
+	if err != nil {
+		return err
+	}
+	body, err := readRecordBody(c, dbName, branch, table)
+	if err != nil {
+		return err
+	}
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.UpsertRecordWithIDWithResponse(c.Context, dbBranch, table, id,
+		&spec.UpsertRecordWithIDParams{IfVersion: ifVersionParam(c)}, body)
+	if err != nil {
+		return fmt.Errorf("Error upserting record: %w", err)
+	}
+	return printResponse(c, resp, resp.Body, nil, func() error {
+		if resp.JSON200 == nil {
+			return fmt.Errorf("Unexpected server response %s", resp.Status())
+		}
+		fmt.Printf("Record [%s] written (version %d)\n", resp.JSON200.Id, resp.JSON200.Xata.Version)
+		return nil
+	})
+}
+
+func deleteRecord(c *cli.Context) error {
+	table, id, err := recordArgs(c, true)
+	if err != nil {
+		return err
+	}
+	force := c.Bool("force")
+	if interactive, reason := isInteractiveWithReason(c); !force && !interactive {
+		return fmt.Errorf("Deleting a record asks for confirmation but %s. Use --force to delete without asking.", reason)
+	}
+	dbBranch, _, _, err := getDBBranchParam(c)
+	if err != nil {
+		return err
+	}
+
+	if !force {
+		yes := false
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("Delete record [%s] from table [%s] of [%s]?", id, table, dbBranch),
+			Default: false,
+		}
+		survey.AskOne(prompt, &yes)
+		if !yes {
+			return nil
+		}
+	}
+
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+	resp, err := client.DeleteRecordWithResponse(c.Context, dbBranch, table, id)
+	if err != nil {
+		return fmt.Errorf("Error deleting record: %w", err)
+	}
+	return printResponse(c, resp, resp.Body, nil, func() error {
+		fmt.Printf("Record [%s] deleted\n", id)
+		return nil
+	})
+}
@@ -0,0 +1,317 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// editorCommand returns the user's editor, from $VISUAL or $EDITOR.
+func editorCommand() string {
+	if editor := os.Getenv("VISUAL"); editor != "" {
+		return editor
+	}
+	if editor := os.Getenv("EDITOR"); editor != "" {
+		return editor
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// encodeRecordDocument formats the columns of a record for editing, as YAML
+// with a header comment or as indented JSON.
+func encodeRecordDocument(values map[string]interface{}, format, header string) ([]byte, error) {
+	if format == "json" {
+		out, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	}
+
+	var buf bytes.Buffer
+	for _, line := range strings.Split(header, "\n") {
+		fmt.Fprintf(&buf, "# %s\n", line)
+	}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeRecordDocument parses an edited record. YAML values are converted to
+// what they'd be in JSON, so that they are coerced the same way.
+func decodeRecordDocument(data []byte, format string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if format == "json" {
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, err
+		}
+		return values, nil
+	}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	out, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	values = map[string]interface{}{}
+	if err := json.Unmarshal(out, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// changedFields validates the edited record against the table columns and
+// returns the columns whose value changed, with nil for removed columns.
+func changedFields(table spec.Table, original, edited map[string]interface{}) (map[string]interface{}, error) {
+	before, err := coerceRecord(table.Name, table.Columns, original)
+	if err != nil {
+		return nil, err
+	}
+	after, err := coerceRecord(table.Name, table.Columns, edited)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]interface{}{}
+	for name, value := range after {
+		if old, ok := before[name]; !ok || !jsonEqual(old, value) {
+			changes[name] = value
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; ok {
+			continue
+		}
+		if column, _ := findColumn(table.Columns, name); column.Required {
+			return nil, fmt.Errorf("Column %s.%s is required and can't be removed", table.Name, name)
+		}
+		changes[name] = nil
+	}
+	for name, value := range changes {
+		if column, _ := findColumn(table.Columns, name); column.Required && value == nil {
+			return nil, fmt.Errorf("Column %s.%s is required and can't be null", table.Name, name)
+		}
+	}
+	return changes, nil
+}
+
+// jsonEqual compares two values as they would be sent to the API.
+func jsonEqual(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return bytes.Equal(ja, jb)
+}
+
+// editConflicts lists the changed columns that were also changed on the
+// server since the record was fetched.
+func editConflicts(original, current, changes map[string]interface{}) []string {
+	conflicts := []string{}
+	for _, name := range sortedKeys(changes) {
+		if jsonEqual(original[name], current[name]) || jsonEqual(changes[name], current[name]) {
+			continue
+		}
+		conflicts = append(conflicts, fmt.Sprintf("%s: was %s, now %s on the server, %s in your edit",
+			name, conflictValue(original[name]), conflictValue(current[name]), conflictValue(changes[name])))
+	}
+	return conflicts
+}
+
+func conflictValue(value interface{}) string {
+	if value == nil {
+		return "unset"
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(out)
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func fetchRecord(c *cli.Context, client *spec.ClientWithResponses, dbBranch spec.DBBranchNameParam,
+	table spec.TableNameParam, id spec.RecordIDParam) (*spec.Record, error) {
+	resp, err := client.GetRecordWithResponse(c.Context, dbBranch, table, id, spec.GetRecordJSONRequestBody{})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting record: %w", err)
+	}
+	if resp.JSON401 != nil {
+		return nil, ErrorUnauthorized{message: resp.JSON401.Message}
+	}
+	if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+		return nil, fmt.Errorf("Error getting record: %s: %s", resp.Status(), getMessage(resp.Body))
+	}
+	return resp.JSON200, nil
+}
+
+// runEditor opens the file in the user's editor and waits for it to exit.
+func runEditor(filename string) error {
+	editor := editorCommand()
+	cmd := buildCommand(fmt.Sprintf("%s %q", editor, filename))
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error running editor '%s': %w", editor, err)
+	}
+	return nil
+}
+
+// editRecord opens a record in $EDITOR and updates the columns that were
+// changed, refusing if the record changed on the server in the meantime.
+func editRecord(c *cli.Context) error {
+	table, id, err := recordArgs(c, true)
+	if err != nil {
+		return err
+	}
+	if interactive, reason := isInteractiveWithReason(c); !interactive {
+		return fmt.Errorf("Editing a record is interactive but %s. Use `xata records update` instead.", reason)
+	}
+	format := c.String("format")
+	if format != "yaml" && format != "json" {
+		return fmt.Errorf("Invalid --format %s: expected yaml or json", format)
+	}
+
+	dbBranch, dbName, branch, err := getDBBranchParam(c)
+	if err != nil {
+		return err
+	}
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+	details, err := getBranchDetails(c, dbName, branch)
+	if err != nil {
+		return err
+	}
+	schemaTable, ok := tablesByName(details.Schema)[string(table)]
+	if !ok {
+		return fmt.Errorf("Table [%s] doesn't exist in branch [%s:%s]", table, dbName, branch)
+	}
+
+	record, err := fetchRecord(c, client, dbBranch, table, id)
+	if err != nil {
+		return err
+	}
+	original := record.AdditionalProperties
+	header := fmt.Sprintf("Record [%s] of table [%s] in [%s], version %d.\nSave and exit to update the changed columns, or empty the file to cancel.",
+		id, table, dbBranch, record.Xata.Version)
+	content, err := encodeRecordDocument(original, format, header)
+	if err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile("", fmt.Sprintf("xata-%s-*.%s", table, format))
+	if err != nil {
+		return err
+	}
+	filename := file.Name()
+	file.Close()
+	keepFile := false
+	defer func() {
+		if !keepFile {
+			os.Remove(filename)
+		}
+	}()
+
+	var changes map[string]interface{}
+	for {
+		if err := ioutil.WriteFile(filename, content, 0600); err != nil {
+			return err
+		}
+		if err := runEditor(filename); err != nil {
+			return err
+		}
+		edited, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(edited)) == 0 {
+			fmt.Println("Empty record, edit cancelled.")
+			return nil
+		}
+		content = edited
+
+		values, err := decodeRecordDocument(edited, format)
+		if err == nil {
+			changes, err = changedFields(schemaTable, original, values)
+		}
+		if err == nil {
+			break
+		}
+		fmt.Printf("Invalid record: %s\n", err)
+		again := true
+		survey.AskOne(&survey.Confirm{Message: "Edit the record again?", Default: true}, &again)
+		if !again {
+			return fmt.Errorf("Edit cancelled, the record wasn't updated")
+		}
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("No changes.")
+		return nil
+	}
+
+	version := record.Xata.Version
+	resp, err := client.UpdateRecordWithIDWithResponse(c.Context, dbBranch, table, id,
+		&spec.UpdateRecordWithIDParams{IfVersion: &version}, changes)
+	if err != nil {
+		return fmt.Errorf("Error updating record: %w", err)
+	}
+	if resp.JSON422 != nil || resp.StatusCode() == http.StatusConflict {
+		keepFile = true
+		current, err := fetchRecord(c, client, dbBranch, table, id)
+		if err != nil {
+			return err
+		}
+		red := color.New(color.FgRed)
+		if c.Bool("nocolor") {
+			red.DisableColor()
+		}
+		red.Printf("Conflict: record [%s] was changed on the server while you were editing it (version %d, now %d).\n",
+			id, version, current.Xata.Version)
+		for _, conflict := range editConflicts(original, current.AdditionalProperties, changes) {
+			red.Printf("  %s\n", conflict)
+		}
+		return fmt.Errorf("The record wasn't updated. Your edit is saved in %s", filename)
+	}
+	return printResponse(c, resp, resp.Body, nil, func() error {
+		if resp.JSON200 == nil {
+			return fmt.Errorf("Unexpected server response %s", resp.Status())
+		}
+		fmt.Printf("Record [%s] updated (version %d): %s\n", resp.JSON200.Id, resp.JSON200.Xata.Version,
+			strings.Join(sortedKeys(changes), ", "))
+		return nil
+	})
+}
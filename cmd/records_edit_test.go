@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestRecordDocumentRoundTrip(t *testing.T) {
+	record := map[string]interface{}{
+		"name":    "Alice",
+		"age":     float64(42),
+		"tags":    []interface{}{"a", "b"},
+		"address": map[string]interface{}{"zipcode": float64(123)},
+	}
+	for _, format := range []string{"yaml", "json"} {
+		out, err := encodeRecordDocument(record, format, "Record [rec_1]\nSave and exit")
+		require.NoError(t, err)
+		if format == "yaml" {
+			require.True(t, strings.HasPrefix(string(out), "# Record [rec_1]\n# Save and exit\n"))
+		}
+		decoded, err := decodeRecordDocument(out, format)
+		require.NoError(t, err)
+		require.Equal(t, record, decoded)
+	}
+
+	_, err := decodeRecordDocument([]byte("name: [unclosed"), "yaml")
+	require.Error(t, err)
+}
+
+func TestChangedFields(t *testing.T) {
+	table := usersTable
+	table.Columns = append([]spec.Column{{Name: "email", Type: spec.ColumnTypeEmail, Required: true}}, table.Columns...)
+	original := map[string]interface{}{
+		"email":   "alice@example.com",
+		"name":    "Alice",
+		"age":     float64(42),
+		"team":    map[string]interface{}{"id": "rec_1"},
+		"address": map[string]interface{}{"zipcode": float64(123)},
+	}
+
+	changes, err := changedFields(table, original, map[string]interface{}{
+		"email":   "alice@example.com",
+		"age":     float64(43),
+		"team":    "rec_1",
+		"address": map[string]interface{}{"zipcode": float64(123)},
+		"active":  true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"age": int64(43), "active": true, "name": nil}, changes)
+
+	_, err = changedFields(table, original, map[string]interface{}{"name": "Alice"})
+	require.EqualError(t, err, "Column users.email is required and can't be removed")
+	_, err = changedFields(table, original, map[string]interface{}{"email": nil})
+	require.EqualError(t, err, "Column users.email is required and can't be null")
+	_, err = changedFields(table, original, map[string]interface{}{"email": "a@b.c", "age": "old"})
+	require.EqualError(t, err, "Invalid value old for column users.age of type int")
+}
+
+func TestEditConflicts(t *testing.T) {
+	original := map[string]interface{}{"name": "Alice", "age": float64(42), "score": float64(1)}
+	current := map[string]interface{}{"name": "Alicia", "age": float64(42), "score": float64(2)}
+	changes := map[string]interface{}{"name": "Ali", "age": int64(43), "score": float64(2)}
+	require.Equal(t, []string{`name: was "Alice", now "Alicia" on the server, "Ali" in your edit`},
+		editConflicts(original, current, changes))
+}
@@ -0,0 +1,460 @@
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/urfave/cli/v2"
+)
+
+// isBackupFile returns true for the names of the files a backup archive
+// holds, which are the only ones extracted on restore.
+func isBackupFile(name string) bool {
+	if name == backupManifestName || name == backupSchemaName {
+		return true
+	}
+	table := strings.TrimPrefix(name, "tables/")
+	return fs.ValidPath(name) && table != name && !strings.Contains(table, "/") && strings.HasSuffix(table, ".ndjson")
+}
+
+// openArchive opens a zip, tar or gzipped tar backup archive. Tar archives
+// are extracted to a temporary directory, removed by the returned function.
+func openArchive(filename string) (fs.FS, func() error, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	magic := make([]byte, 4)
+	n, _ := io.ReadFull(file, magic)
+	magic = magic[:n]
+	if bytes.HasPrefix(magic, []byte("PK\x03\x04")) {
+		file.Close()
+		archive, err := zip.OpenReader(filename)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error reading backup archive: %w", err)
+		}
+		return archive, archive.Close, nil
+	}
+	defer file.Close()
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+
+	var r io.Reader = bufio.NewReader(file)
+	if bytes.HasPrefix(magic, []byte{0x1f, 0x8b}) {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error reading backup archive: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+	dir, err := ioutil.TempDir("", "xata-restore")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() error { return os.RemoveAll(dir) }
+	if err := extractTar(r, dir); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("Error reading backup archive: %w", err)
+	}
+	return os.DirFS(dir), cleanup, nil
+}
+
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	if err := os.Mkdir(filepath.Join(dir, "tables"), 0755); err != nil {
+		return err
+	}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg || !isBackupFile(header.Name) {
+			continue
+		}
+		file, err := os.Create(filepath.Join(dir, filepath.FromSlash(header.Name)))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(file, tr)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func readArchiveJSON(fsys fs.FS, name string, v interface{}) error {
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("Error reading %s from backup archive: %w", name, err)
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("Error parsing %s from backup archive: %w", name, err)
+	}
+	return nil
+}
+
+// columnLinks returns the tables the column links to, including from the
+// columns of objects.
+func columnLinks(column spec.Column) []string {
+	if column.Type == spec.ColumnTypeLink && column.Link != nil {
+		return []string{column.Link.Table}
+	}
+	links := []string{}
+	for _, nested := range column.Columns {
+		links = append(links, columnLinks(nested)...)
+	}
+	return links
+}
+
+// loadOrder orders the tables so that linked tables are loaded before the
+// tables linking to them. Links to the same table, or in a cycle of links,
+// can't be set when inserting the records: their columns are returned by
+// table, to be set once all the records are loaded.
+func loadOrder(tables []spec.Table) ([]spec.Table, map[string][]string) {
+	remaining := map[string]spec.Table{}
+	for _, table := range tables {
+		remaining[table.Name] = table
+	}
+	// waitsFor returns the first table not loaded yet the table links to
+	waitsFor := func(table spec.Table) (string, bool) {
+		for _, column := range table.Columns {
+			for _, target := range columnLinks(column) {
+				if _, ok := remaining[target]; ok && target != table.Name {
+					return target, true
+				}
+			}
+		}
+		return "", false
+	}
+
+	order := []spec.Table{}
+	deferred := map[string][]string{}
+	for len(order) < len(tables) {
+		var next spec.Table
+		for _, table := range tables {
+			if _, ok := remaining[table.Name]; !ok {
+				continue
+			}
+			if _, waits := waitsFor(table); !waits {
+				next = table
+				break
+			}
+		}
+		if next.Name == "" {
+			// all the tables left wait for another one: follow the links to
+			// a table in a cycle, and break it there
+			visited := map[string]bool{}
+			for _, table := range tables {
+				if _, ok := remaining[table.Name]; ok {
+					next = table
+					break
+				}
+			}
+			for !visited[next.Name] {
+				visited[next.Name] = true
+				target, _ := waitsFor(next)
+				next = remaining[target]
+			}
+		}
+
+		delete(remaining, next.Name)
+		for _, column := range next.Columns {
+			for _, target := range columnLinks(column) {
+				if _, ok := remaining[target]; ok || target == next.Name {
+					deferred[next.Name] = append(deferred[next.Name], column.Name)
+					break
+				}
+			}
+		}
+		order = append(order, next)
+	}
+	return order, deferred
+}
+
+// splitDeferred removes the deferred columns from the record and returns
+// their values, if any are set.
+func splitDeferred(record map[string]interface{}, columns []string) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, column := range columns {
+		if value, ok := record[column]; ok {
+			delete(record, column)
+			if value != nil {
+				values[column] = value
+			}
+		}
+	}
+	return values
+}
+
+// loadRecords reads all the rows and sends them with concurrent requests. It
+// stops at the first invalid row or failed request, and returns how many rows
+// were sent.
+func loadRecords(rows rowReader, concurrency int, send func(row *importRow) error) (int, error) {
+	jobs := make(chan *importRow)
+	errs := make(chan error, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := range jobs {
+				if err := send(row); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	count := 0
+	var err error
+read:
+	for {
+		var row *importRow
+		row, err = rows.Read()
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err == nil && row.err != nil {
+			err = fmt.Errorf("line %d: %w", row.line, row.err)
+		}
+		if err != nil {
+			break
+		}
+		select {
+		case jobs <- row:
+			count++
+		case err = <-errs:
+			break read
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	return count, err
+}
+
+// insertWithID inserts a record with its ID, failing if it already exists.
+func insertWithID(c *cli.Context, client *spec.ClientWithResponses, dbBranch spec.DBBranchNameParam,
+	table string, id string, record map[string]interface{}) error {
+	createOnly := true
+	resp, err := client.InsertRecordWithIDWithResponse(c.Context, dbBranch, spec.TableNameParam(table),
+		spec.RecordIDParam(id), &spec.InsertRecordWithIDParams{CreateOnly: &createOnly}, record)
+	if err != nil {
+		return fmt.Errorf("Error inserting record [%s] into table [%s]: %w", id, table, err)
+	}
+	if resp.JSON401 != nil {
+		return ErrorUnauthorized{message: resp.JSON401.Message}
+	}
+	if resp.StatusCode()/100 != 2 {
+		return fmt.Errorf("Error inserting record [%s] into table [%s]: %s: %s", id, table, resp.Status(), getMessage(resp.Body))
+	}
+	return nil
+}
+
+func updateWithID(c *cli.Context, client *spec.ClientWithResponses, dbBranch spec.DBBranchNameParam,
+	table string, id string, values map[string]interface{}) error {
+	resp, err := client.UpdateRecordWithIDWithResponse(c.Context, dbBranch, spec.TableNameParam(table),
+		spec.RecordIDParam(id), &spec.UpdateRecordWithIDParams{}, values)
+	if err != nil {
+		return fmt.Errorf("Error updating record [%s] of table [%s]: %w", id, table, err)
+	}
+	if resp.JSON401 != nil {
+		return ErrorUnauthorized{message: resp.JSON401.Message}
+	}
+	if resp.StatusCode()/100 != 2 {
+		return fmt.Errorf("Error updating record [%s] of table [%s]: %s: %s", id, table, resp.Status(), getMessage(resp.Body))
+	}
+	return nil
+}
+
+// restoreTable inserts the records of a table from the archive. With
+// deferredOnly, it sets the deferred columns of the inserted records instead.
+func restoreTable(c *cli.Context, client *spec.ClientWithResponses, dbBranch spec.DBBranchNameParam, fsys fs.FS,
+	table spec.Table, deferred []string, deferredOnly bool) (int, error) {
+	name := backupTableFile(table.Name)
+	file, err := fsys.Open(name)
+	if err != nil {
+		return 0, fmt.Errorf("Error reading %s from backup archive: %w", name, err)
+	}
+	defer file.Close()
+
+	count, err := loadRecords(newNDJSONRowReader(file, table), c.Int("concurrency"), func(row *importRow) error {
+		id, _ := row.record["id"].(string)
+		if id == "" {
+			return fmt.Errorf("line %d: record without an id", row.line)
+		}
+		delete(row.record, "id")
+		values := splitDeferred(row.record, deferred)
+		if !deferredOnly {
+			return insertWithID(c, client, dbBranch, table.Name, id, row.record)
+		}
+		if len(values) == 0 {
+			return nil
+		}
+		return updateWithID(c, client, dbBranch, table.Name, id, values)
+	})
+	if err != nil {
+		return count, fmt.Errorf("Error restoring table [%s] from %s: %w", table.Name, name, err)
+	}
+	return count, nil
+}
+
+func createRestoreBranch(c *cli.Context, client *spec.ClientWithResponses, dbBranch spec.DBBranchNameParam) error {
+	resp, err := client.CreateBranchWithResponse(c.Context, dbBranch, &spec.CreateBranchParams{}, spec.CreateBranchJSONRequestBody{})
+	if err != nil {
+		return fmt.Errorf("Error creating branch: %w", err)
+	}
+	if resp.JSON401 != nil {
+		return ErrorUnauthorized{message: resp.JSON401.Message}
+	}
+	if resp.StatusCode()/100 != 2 {
+		return fmt.Errorf("Error creating branch: %s: %s", resp.Status(), getMessage(resp.Body))
+	}
+	return nil
+}
+
+// RestoreCommand restores a backup archive into a new branch.
+func RestoreCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("Expected exactly one argument: the backup archive")
+	}
+	target := c.String("branch")
+	if !spec.IsValidIdentifier(target) {
+		return fmt.Errorf("Invalid branch name [%s]", target)
+	}
+	if c.Int("concurrency") < 1 {
+		return fmt.Errorf("Invalid --concurrency %d: must be at least 1", c.Int("concurrency"))
+	}
+	force := c.Bool("force")
+	if interactive, reason := isInteractiveWithReason(c); !force && !interactive {
+		return fmt.Errorf("Restoring asks for confirmation but %s. Use --force to restore without asking.", reason)
+	}
+
+	fsys, closeArchive, err := openArchive(c.Args().First())
+	if err != nil {
+		return err
+	}
+	defer closeArchive()
+	var manifest backupManifest
+	if err := readArchiveJSON(fsys, backupManifestName, &manifest); err != nil {
+		return err
+	}
+	if manifest.FormatVersion > backupFormatVersion {
+		return fmt.Errorf("The backup archive has format version %d, this version of the CLI reads up to %d", manifest.FormatVersion, backupFormatVersion)
+	}
+	var schema spec.Schema
+	if err := readArchiveJSON(fsys, backupSchemaName, &schema); err != nil {
+		return err
+	}
+
+	dbName, _, _, err := getDBNameAndBranch(c)
+	if err != nil {
+		return err
+	}
+	dbBranch := spec.DBBranchNameParam(fmt.Sprintf("%s:%s", dbName, target))
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+	existing, err := client.GetBranchDetailsWithResponse(c.Context, dbBranch)
+	if err != nil {
+		return err
+	}
+	if existing.JSON404 == nil {
+		if err := checkBranchDetails(existing); err != nil {
+			return err
+		}
+		return fmt.Errorf("Branch [%s] already exists, restore into a new branch", dbBranch)
+	}
+
+	total := 0
+	for _, table := range manifest.Tables {
+		total += table.Records
+	}
+	fmt.Printf("Backup of [%s:%s] at migration %s, taken %s: %d records in %d tables\n",
+		manifest.Database, manifest.Branch, manifest.LastMigrationID, manifest.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+		total, len(manifest.Tables))
+	if !force {
+		yes := false
+		message := fmt.Sprintf("Create branch [%s] and restore the backup into it?", dbBranch)
+		survey.AskOne(&survey.Confirm{Message: message, Default: true}, &yes)
+		if !yes {
+			return nil
+		}
+	}
+
+	if err := createRestoreBranch(c, client, dbBranch); err != nil {
+		return err
+	}
+	plan, _, err := planDeploy(c, dbName, target, schema, false)
+	if err != nil {
+		return err
+	}
+	if !isMigrationEmpty(plan.Migration) {
+		if err := executeMigrationPlan(c, dbName, target, plan); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Branch [%s] created with the schema of the backup\n", dbBranch)
+
+	expected := map[string]int{}
+	for _, table := range manifest.Tables {
+		expected[table.Name] = table.Records
+	}
+	order, deferred := loadOrder(schema.Tables)
+	restored := 0
+	for _, table := range order {
+		count, err := restoreTable(c, client, dbBranch, fsys, table, deferred[table.Name], false)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Restored %d records of table [%s]\n", count, table.Name)
+		if count != expected[table.Name] {
+			fmt.Printf("Warning: the manifest lists %d records for table [%s]\n", expected[table.Name], table.Name)
+		}
+		restored += count
+	}
+	for _, table := range order {
+		if len(deferred[table.Name]) == 0 {
+			continue
+		}
+		if _, err := restoreTable(c, client, dbBranch, fsys, table, deferred[table.Name], true); err != nil {
+			return err
+		}
+		fmt.Printf("Restored the links of table [%s]: %s\n", table.Name, strings.Join(deferred[table.Name], ", "))
+	}
+
+	fmt.Printf("Restored %d records in %d tables into [%s]\n", restored, len(order), dbBranch)
+	return nil
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/urfave/cli/v2"
+)
+
+// BlameEntry tells which migration last created or changed a table or
+// column.
+type BlameEntry struct {
+	// Path is the table name, or table.column (table.object.column for the
+	// columns of objects)
+	Path        string `json:"path"`
+	MigrationID string `json:"migrationID"`
+	// Branch is the branch the migration was made on, which is a parent
+	// branch for migrations made before the branch was created
+	Branch      string         `json:"branch"`
+	CreatedAt   *spec.DateTime `json:"createdAt,omitempty"`
+	GitRevision string         `json:"gitRevision,omitempty"`
+}
+
+type branchMigration struct {
+	branch    string
+	migration spec.BranchMigration
+}
+
+// blameSchema replays the migrations, given oldest first, and returns the
+// resulting schema with an entry for each table and column. Tables are
+// attributed to the migration that created or renamed them, and columns to
+// the migration that last added, renamed or changed them.
+func blameSchema(migrations []branchMigration) (spec.Schema, []BlameEntry) {
+	schema := spec.Schema{FormatVersion: schemaVersion, Tables: []spec.Table{}}
+	blame := map[string]BlameEntry{}
+
+	for _, m := range migrations {
+		migration := m.migration
+		if migration.Status == "failed" {
+			continue
+		}
+		entry := BlameEntry{Branch: m.branch, CreatedAt: migration.CreatedAt}
+		if migration.Id != nil {
+			entry.MigrationID = *migration.Id
+		}
+		if migration.LastGitRevision != nil {
+			entry.GitRevision = *migration.LastGitRevision
+		}
+
+		if migration.RemovedTables != nil {
+			for _, table := range *migration.RemovedTables {
+				moveBlame(blame, table, "")
+			}
+		}
+		if migration.RenamedTables != nil {
+			for _, rename := range *migration.RenamedTables {
+				moveBlame(blame, rename.OldName, rename.NewName)
+				blame[rename.NewName] = entry
+			}
+		}
+		if migration.NewTables != nil {
+			for name, table := range migration.NewTables.AdditionalProperties {
+				moveBlame(blame, name, "")
+				blame[name] = entry
+				blameColumns(blame, name, nil, table.Columns, entry)
+			}
+		}
+		if migration.TableMigrations != nil {
+			for table, tableMigration := range migration.TableMigrations.AdditionalProperties {
+				for _, column := range stringList(tableMigration.RemovedColumns) {
+					moveBlame(blame, table+"."+column, "")
+				}
+				if tableMigration.ModifiedColumns != nil {
+					for _, column := range *tableMigration.ModifiedColumns {
+						oldPath, newPath := table+"."+column.Old.Name, table+"."+column.New.Name
+						moveBlame(blame, oldPath, newPath)
+						if column.Old.Name != column.New.Name || len(columnChanges("", column.Old, column.New)) > 0 {
+							blame[newPath] = entry
+						}
+						blameColumns(blame, newPath, column.Old.Columns, column.New.Columns, entry)
+					}
+				}
+				if tableMigration.NewColumns != nil {
+					for name, column := range tableMigration.NewColumns.AdditionalProperties {
+						column.Name = name
+						blameColumns(blame, table, nil, []spec.Column{column}, entry)
+					}
+				}
+			}
+		}
+
+		schema = applyMigration(schema, migration)
+	}
+
+	entries := []BlameEntry{}
+	for _, table := range schema.Tables {
+		entries = append(entries, blameEntry(blame, table.Name))
+		entries = appendColumnBlame(entries, blame, table.Name, table.Columns)
+	}
+	return schema, entries
+}
+
+// blameColumns attributes the new columns under the path to the entry,
+// unless they are identical in the old columns.
+func blameColumns(blame map[string]BlameEntry, path string, old, new []spec.Column, entry BlameEntry) {
+	for _, oldColumn := range old {
+		if _, ok := findColumn(new, oldColumn.Name); !ok {
+			moveBlame(blame, path+"."+oldColumn.Name, "")
+		}
+	}
+	for _, column := range new {
+		columnPath := path + "." + column.Name
+		oldColumn, existed := findColumn(old, column.Name)
+		if !existed || len(columnChanges("", oldColumn, column)) > 0 {
+			blame[columnPath] = entry
+		}
+		blameColumns(blame, columnPath, oldColumn.Columns, column.Columns, entry)
+	}
+}
+
+// moveBlame moves the entries of a path and everything under it to a new
+// path, or deletes them if to is empty.
+func moveBlame(blame map[string]BlameEntry, from, to string) {
+	for path, entry := range blame {
+		if path != from && !strings.HasPrefix(path, from+".") {
+			continue
+		}
+		delete(blame, path)
+		if to != "" {
+			blame[to+strings.TrimPrefix(path, from)] = entry
+		}
+	}
+}
+
+func blameEntry(blame map[string]BlameEntry, path string) BlameEntry {
+	entry := blame[path]
+	entry.Path = path
+	return entry
+}
+
+func appendColumnBlame(entries []BlameEntry, blame map[string]BlameEntry, path string, columns []spec.Column) []BlameEntry {
+	for _, column := range columns {
+		columnPath := path + "." + column.Name
+		entries = append(entries, blameEntry(blame, columnPath))
+		entries = appendColumnBlame(entries, blame, columnPath, column.Columns)
+	}
+	return entries
+}
+
+// SchemaBlameCommand annotates every table and column of the branch schema
+// with the migration that last created or changed it.
+func SchemaBlameCommand(c *cli.Context) error {
+	dbName, _, branch, err := getDBNameAndBranch(c)
+	if err != nil {
+		return err
+	}
+	if c.IsSet("branch") {
+		branch = c.String("branch")
+	}
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+
+	// collect newest first, tracking the branch each migration was made on
+	migrations := []branchMigration{}
+	currentBranch := branch
+	err = walkMigrationHistory(c.Context, client, dbName, branch, "", true,
+		func(migration *spec.BranchMigration, origin *spec.StartedFromMetadata) bool {
+			if origin != nil {
+				currentBranch = string(origin.BranchName)
+				return true
+			}
+			migrations = append(migrations, branchMigration{branch: currentBranch, migration: *migration})
+			return true
+		})
+	if err != nil {
+		return err
+	}
+	for i, j := 0, len(migrations)-1; i < j; i, j = i+1, j-1 {
+		migrations[i], migrations[j] = migrations[j], migrations[i]
+	}
+
+	_, entries := blameSchema(migrations)
+
+	if c.Bool("json") {
+		out, err := json.Marshal(entries)
+		if err != nil {
+			return err
+		}
+		return printJSON(c, out)
+	}
+
+	rows := make([][]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		date, sha := "", entry.GitRevision
+		if entry.CreatedAt != nil {
+			date = time.Time(*entry.CreatedAt).UTC().Format("2006-01-02 15:04")
+		}
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		rows = append(rows, []interface{}{entry.Path, entry.MigrationID, date, sha, entry.Branch})
+	}
+	if len(rows) == 0 {
+		fmt.Printf("Branch [%s:%s] has no tables.\n", dbName, branch)
+		return nil
+	}
+	printTable([]string{"Path", "Migration", "Date", "Git", "Branch"}, rows)
+	return nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestBlameSchema(t *testing.T) {
+	v1 := spec.Schema{Tables: []spec.Table{
+		{Name: "users", Columns: []spec.Column{
+			{Name: "name", Type: spec.ColumnTypeString},
+			{Name: "address", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+				{Name: "street", Type: spec.ColumnTypeString},
+				{Name: "zip", Type: spec.ColumnTypeInt},
+			}},
+		}},
+	}}
+	v2 := spec.Schema{Tables: []spec.Table{
+		{Name: "people", RenamedFrom: "users", Columns: []spec.Column{
+			{Name: "full_name", RenamedFrom: "name", Type: spec.ColumnTypeString},
+			{Name: "address", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+				{Name: "street", Type: spec.ColumnTypeString},
+				{Name: "zip", Type: spec.ColumnTypeString},
+			}},
+		}},
+	}}
+	v3 := spec.Schema{FormatVersion: "1.0", Tables: []spec.Table{
+		{Name: "people", Columns: []spec.Column{
+			{Name: "full_name", Type: spec.ColumnTypeString},
+			{Name: "address", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+				{Name: "street", Type: spec.ColumnTypeString},
+				{Name: "zip", Type: spec.ColumnTypeString},
+			}},
+			{Name: "email", Type: spec.ColumnTypeEmail},
+		}},
+	}}
+
+	m1 := PlanMigration(spec.Schema{}, v1)
+	m1.Id = stringPtr("mig_1")
+	m1.LastGitRevision = stringPtr("aaaaaaa")
+	m2 := PlanMigration(v1, v2)
+	m2.Id = stringPtr("mig_2")
+	m3 := PlanMigration(v2, v3)
+	m3.Id = stringPtr("mig_3")
+
+	schema, entries := blameSchema([]branchMigration{
+		{branch: "main", migration: m1},
+		{branch: "main", migration: m2},
+		{branch: "feature", migration: m3},
+	})
+	require.True(t, schemasEqual(v3, schema))
+
+	blamed := map[string]string{}
+	for _, entry := range entries {
+		blamed[entry.Path] = entry.MigrationID + "@" + entry.Branch
+	}
+	require.Equal(t, map[string]string{
+		"people":                "mig_2@main",
+		"people.full_name":      "mig_2@main",
+		"people.address":        "mig_2@main",
+		"people.address.street": "mig_1@main",
+		"people.address.zip":    "mig_2@main",
+		"people.email":          "mig_3@feature",
+	}, blamed)
+	require.Equal(t, "people.address.street", entries[3].Path)
+	require.Equal(t, "aaaaaaa", entries[3].GitRevision)
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+
+	"github.com/xataio/cli/client/spec"
+	"github.com/xataio/cli/filesystem"
+
+	"github.com/urfave/cli/v2"
+)
+
+// SchemaConvertCommand rewrites the schema file in a different format and
+// switches the schemaFileFormat setting to it.
+func SchemaConvertCommand(c *cli.Context) error {
+	to := c.String("to")
+	if to != SettingsJSON && to != SettingsYAML && to != SettingsXata {
+		return fmt.Errorf("--to must be either `json`, `yaml` or `xata`")
+	}
+
+	oldFile, newFile, err := convertSchemaFile(c.String("dir"), to, c.Bool("force"))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Schema converted from %s to %s\n", oldFile, newFile)
+	return nil
+}
+
+// convertSchemaFile writes the schema of dir in the given format, points the
+// settings to the new file and removes the old one. It returns the old and
+// the new schema file.
+func convertSchemaFile(dir, to string, force bool) (string, string, error) {
+	settings, err := ReadSettings(dir)
+	if err != nil {
+		return "", "", err
+	}
+	if settings.SchemaFileFormat == to {
+		return "", "", fmt.Errorf("The schema file is already in the %s format.", to)
+	}
+
+	schema, oldFile, err := readSchemaFile(dir)
+	if err != nil {
+		return "", "", err
+	}
+
+	newSettings := *settings
+	newSettings.SchemaFileFormat = to
+	newFile := SchemaFilePath(dir, &newSettings)
+
+	exists, err := filesystem.FileExists(newFile)
+	if err != nil {
+		return "", "", err
+	}
+	if exists && !force {
+		return "", "", fmt.Errorf("File %s already exists, so I am not overwriting it. Use -f if you are sure.", newFile)
+	}
+
+	converted, err := marshalSchema(to, schema)
+	if err != nil {
+		return "", "", fmt.Errorf("converting schema: %w", err)
+	}
+
+	// make sure nothing is lost in translation before touching any file
+	reparsed, err := unmarshalSchema(to, newFile, converted)
+	if err != nil {
+		return "", "", fmt.Errorf("The converted schema can't be read back: %w", err)
+	}
+	if !schemasEqual(schema, reparsed) {
+		return "", "", fmt.Errorf("Converting %s to the %s format would change the schema, so I am not converting it.", oldFile, to)
+	}
+
+	err = replaceFile(newFile, converted, func() error {
+		return writeSettings(dir, newSettings)
+	})
+	if err != nil {
+		return "", "", err
+	}
+	err = os.Remove(oldFile)
+	if err != nil {
+		return "", "", fmt.Errorf("removing %s: %w", oldFile, err)
+	}
+	return oldFile, newFile, nil
+}
+
+// replaceFile writes data to filename and then calls commit. If commit fails,
+// filename is put back the way it was: restored if it existed, removed if it
+// didn't.
+func replaceFile(filename string, data []byte, commit func() error) error {
+	backup, err := ioutil.ReadFile(filename)
+	existed := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading file: %w", err)
+	}
+
+	err = filesystem.WriteFileAtomic(filename, data, 0644)
+	if err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+
+	err = commit()
+	if err == nil {
+		return nil
+	}
+	if existed {
+		if restoreErr := filesystem.WriteFileAtomic(filename, backup, 0644); restoreErr != nil {
+			return fmt.Errorf("%w (restoring %s also failed: %s)", err, filename, restoreErr)
+		}
+	} else {
+		os.Remove(filename)
+	}
+	return err
+}
+
+// schemasEqual returns true if both schemas describe the same tables and
+// columns, no matter if empty lists are nil or not.
+func schemasEqual(a, b spec.Schema) bool {
+	return reflect.DeepEqual(normalizeSchema(a), normalizeSchema(b))
+}
+
+func normalizeSchema(schema spec.Schema) spec.Schema {
+	tables := make([]spec.Table, 0, len(schema.Tables))
+	for _, table := range schema.Tables {
+		table.Columns = normalizeColumns(table.Columns)
+		tables = append(tables, table)
+	}
+	schema.Tables = tables
+	return schema
+}
+
+func normalizeColumns(columns []spec.Column) []spec.Column {
+	res := make([]spec.Column, 0, len(columns))
+	for _, column := range columns {
+		if column.Type == spec.ColumnTypeObject || len(column.Columns) > 0 {
+			column.Columns = normalizeColumns(column.Columns)
+		}
+		// the per type settings are empty, so they only differ in
+		// whether the source format spelled them out
+		column.Object = nil
+		column.String = nil
+		column.Bool = nil
+		column.Email = nil
+		column.Text = nil
+		column.Multiple = nil
+		column.Int = nil
+		column.Float = nil
+		res = append(res, column)
+	}
+	return res
+}
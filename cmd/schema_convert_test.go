@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func setupConvertDir(t *testing.T, schema spec.Schema) string {
+	dir := t.TempDir()
+	require.NoError(t, writeSettings(dir, SettingsFile{SchemaFileFormat: SettingsJSON, DBName: "test"}))
+	data, err := marshalSchema(SettingsJSON, schema)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, "schema.json"), data, 0644))
+	return dir
+}
+
+func TestConvertSchemaFileRoundTrip(t *testing.T) {
+	var schema spec.Schema
+	require.NoError(t, json.Unmarshal(defaultSchema, &schema))
+	dir := setupConvertDir(t, schema)
+
+	oldFile, newFile, err := convertSchemaFile(dir, SettingsYAML, false)
+	require.NoError(t, err)
+	require.Equal(t, path.Join(dir, "schema.json"), oldFile)
+	require.Equal(t, path.Join(dir, "schema.yaml"), newFile)
+	require.NoFileExists(t, oldFile)
+
+	_, newFile, err = convertSchemaFile(dir, SettingsXata, false)
+	require.NoError(t, err)
+	require.Equal(t, path.Join(dir, "schema.xata"), newFile)
+	require.NoFileExists(t, path.Join(dir, "schema.yaml"))
+
+	settings, err := ReadSettings(dir)
+	require.NoError(t, err)
+	require.Equal(t, SettingsXata, settings.SchemaFileFormat)
+	require.Equal(t, "test", settings.DBName)
+
+	converted, _, err := readSchemaFile(dir)
+	require.NoError(t, err)
+	require.True(t, schemasEqual(schema, converted))
+}
+
+func TestConvertSchemaFileLossy(t *testing.T) {
+	// lookup fields have no .xata syntax
+	dir := setupConvertDir(t, spec.Schema{Tables: []spec.Table{
+		{Name: "users", Columns: []spec.Column{
+			{Name: "team", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "users", LookupFields: []string{"name"}}},
+		}},
+	}})
+
+	_, _, err := convertSchemaFile(dir, SettingsXata, false)
+	require.EqualError(t, err, "Converting "+path.Join(dir, "schema.json")+" to the xata format would change the schema, so I am not converting it.")
+	require.FileExists(t, path.Join(dir, "schema.json"))
+	require.NoFileExists(t, path.Join(dir, "schema.xata"))
+}
+
+func TestConvertSchemaFileExistingTarget(t *testing.T) {
+	var schema spec.Schema
+	require.NoError(t, json.Unmarshal(defaultSchema, &schema))
+	dir := setupConvertDir(t, schema)
+	target := path.Join(dir, "schema.yaml")
+	require.NoError(t, ioutil.WriteFile(target, []byte("keep me"), 0644))
+
+	_, _, err := convertSchemaFile(dir, SettingsYAML, false)
+	require.EqualError(t, err, "File "+target+" already exists, so I am not overwriting it. Use -f if you are sure.")
+	data, err := ioutil.ReadFile(target)
+	require.NoError(t, err)
+	require.Equal(t, "keep me", string(data))
+
+	_, _, err = convertSchemaFile(dir, SettingsYAML, true)
+	require.NoError(t, err)
+	data, err = ioutil.ReadFile(target)
+	require.NoError(t, err)
+	require.NotEqual(t, "keep me", string(data))
+}
+
+func TestReplaceFileRollback(t *testing.T) {
+	dir := t.TempDir()
+	existing := path.Join(dir, "existing")
+	require.NoError(t, ioutil.WriteFile(existing, []byte("original"), 0644))
+
+	err := replaceFile(existing, []byte("converted"), func() error {
+		data, err := ioutil.ReadFile(existing)
+		require.NoError(t, err)
+		require.Equal(t, "converted", string(data))
+		return errors.New("writing config file: disk full")
+	})
+	require.EqualError(t, err, "writing config file: disk full")
+	data, err := ioutil.ReadFile(existing)
+	require.NoError(t, err)
+	require.Equal(t, "original", string(data))
+
+	created := path.Join(dir, "created")
+	err = replaceFile(created, []byte("converted"), func() error {
+		return errors.New("writing config file: disk full")
+	})
+	require.Error(t, err)
+	require.NoFileExists(t, created)
+
+	err = replaceFile(existing, []byte("converted"), func() error { return nil })
+	require.NoError(t, err)
+	data, err = ioutil.ReadFile(existing)
+	require.NoError(t, err)
+	require.Equal(t, "converted", string(data))
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/urfave/cli/v2"
+)
+
+// SchemaDiffCommand compares the local schema file with the schema of a
+// remote branch, the schema file at a git revision, or another schema file.
+func SchemaDiffCommand(c *cli.Context) error {
+	dir := c.String("dir")
+	settings, err := ReadSettings(dir)
+	if err != nil {
+		return err
+	}
+	local, schemaFile, err := readSchemaFile(dir)
+	if err != nil {
+		return err
+	}
+	if err := checkRenameHints(local); err != nil {
+		return err
+	}
+
+	source, err := chooseSchemaDiffSource(c.String("branch"), c.String("rev"), c.String("file"))
+	if err != nil {
+		return err
+	}
+
+	var other spec.Schema
+	var otherName string
+	if source.kind == schemaDiffRemote {
+		dbName, _, branch, err := getDBNameAndBranch(c)
+		if err != nil {
+			return err
+		}
+		if source.name != "" {
+			branch = source.name
+		}
+		details, err := getBranchDetails(c, dbName, branch)
+		if err != nil {
+			return err
+		}
+		other = details.Schema
+		// the server doesn't return the version, pull writes it like this
+		other.FormatVersion = schemaVersion
+		otherName = fmt.Sprintf("%s:%s", dbName, branch)
+	} else {
+		other, otherName, err = readSchemaDiffSource(source, settings.SchemaFileFormat, schemaFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Bool("unified") {
+		from, err := marshalSchema(settings.SchemaFileFormat, other)
+		if err != nil {
+			return err
+		}
+		to, err := marshalSchema(settings.SchemaFileFormat, local)
+		if err != nil {
+			return err
+		}
+		fmt.Print(unifiedDiff(otherName, schemaFile, string(from), string(to)))
+		return nil
+	}
+
+	migration := PlanMigration(other, local)
+	if c.Bool("json") {
+		out, err := json.Marshal(migration)
+		if err != nil {
+			return err
+		}
+		return printJSON(c, out)
+	}
+
+	if isMigrationEmpty(migration) {
+		fmt.Printf("No differences between %s and %s.\n", otherName, schemaFile)
+		return nil
+	}
+	fmt.Printf("Changes from %s to %s:\n\n", otherName, schemaFile)
+	PrintMigration(migration, c.Bool("nocolor"))
+	return nil
+}
+
+const (
+	schemaDiffRemote = "remote"
+	schemaDiffGit    = "git"
+	schemaDiffFile   = "file"
+)
+
+// schemaDiffSource is what the local schema file gets compared with: a
+// remote branch (the current one if name is empty), a git revision of the
+// schema file, or another schema file.
+type schemaDiffSource struct {
+	kind string
+	name string
+}
+
+// chooseSchemaDiffSource picks the source from the --branch, --rev and
+// --file flags, of which at most one can be set.
+func chooseSchemaDiffSource(branch, rev, file string) (schemaDiffSource, error) {
+	sources := []schemaDiffSource{}
+	if branch != "" {
+		sources = append(sources, schemaDiffSource{schemaDiffRemote, branch})
+	}
+	if rev != "" {
+		sources = append(sources, schemaDiffSource{schemaDiffGit, rev})
+	}
+	if file != "" {
+		sources = append(sources, schemaDiffSource{schemaDiffFile, file})
+	}
+	switch len(sources) {
+	case 0:
+		return schemaDiffSource{kind: schemaDiffRemote}, nil
+	case 1:
+		return sources[0], nil
+	}
+	return schemaDiffSource{}, fmt.Errorf("Only one of --branch, --rev and --file can be used.")
+}
+
+// readSchemaDiffSource reads the schema of a git or file source, and returns
+// it with the name to show for it.
+func readSchemaDiffSource(source schemaDiffSource, format, schemaFile string) (spec.Schema, string, error) {
+	switch source.kind {
+	case schemaDiffGit:
+		bytes, err := GitShowFile(source.name, schemaFile)
+		if err != nil {
+			return spec.Schema{}, "", err
+		}
+		name := fmt.Sprintf("%s@%s", schemaFile, source.name)
+		schema, err := unmarshalSchema(format, name, bytes)
+		if err != nil {
+			return spec.Schema{}, "", err
+		}
+		return schema, name, nil
+	case schemaDiffFile:
+		schema, err := readSchemaFileWithFormat(source.name)
+		if err != nil {
+			return spec.Schema{}, "", err
+		}
+		return schema, source.name, nil
+	}
+	return spec.Schema{}, "", fmt.Errorf("Can't read the schema of a %s source locally", source.kind)
+}
+
+// schemaFormatFromFilename guesses the schema format from the file extension.
+func schemaFormatFromFilename(filename string) (string, error) {
+	switch filepath.Ext(filename) {
+	case ".json":
+		return SettingsJSON, nil
+	case ".yaml", ".yml":
+		return SettingsYAML, nil
+	case ".xata":
+		return SettingsXata, nil
+	}
+	return "", fmt.Errorf("Can't tell the format of %s, the extension must be .json, .yaml or .xata", filename)
+}
+
+// readSchemaFileWithFormat reads any schema file, using its extension to
+// pick the format.
+func readSchemaFileWithFormat(filename string) (spec.Schema, error) {
+	format, err := schemaFormatFromFilename(filename)
+	if err != nil {
+		return spec.Schema{}, err
+	}
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return spec.Schema{}, fmt.Errorf("reading file: %w", err)
+	}
+	return unmarshalSchema(format, filename, bytes)
+}
+
+func getBranchDetails(c *cli.Context, dbName, branch string) (*spec.DBBranch, error) {
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return nil, err
+	}
+
+	dbbranch := spec.DBBranchNameParam(fmt.Sprintf("%s:%s", dbName, branch))
+	resp, err := client.GetBranchDetailsWithResponse(c.Context, dbbranch)
+	if err != nil {
+		return nil, err
+	}
+	err = checkBranchDetails(resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.JSON200, nil
+}
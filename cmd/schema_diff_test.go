@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestChooseSchemaDiffSource(t *testing.T) {
+	tests := []struct {
+		name              string
+		branch, rev, file string
+		expected          schemaDiffSource
+		err               string
+	}{
+		{name: "current branch", expected: schemaDiffSource{kind: schemaDiffRemote}},
+		{name: "other branch", branch: "dev", expected: schemaDiffSource{schemaDiffRemote, "dev"}},
+		{name: "git revision", rev: "HEAD~1", expected: schemaDiffSource{schemaDiffGit, "HEAD~1"}},
+		{name: "file", file: "old.json", expected: schemaDiffSource{schemaDiffFile, "old.json"}},
+		{name: "several", branch: "dev", file: "old.json", err: "Only one of --branch, --rev and --file can be used."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source, err := chooseSchemaDiffSource(test.branch, test.rev, test.file)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, source)
+		})
+	}
+}
+
+var diffSourceSchema = spec.Schema{FormatVersion: "1.0", Tables: []spec.Table{
+	{Name: "users", Columns: []spec.Column{{Name: "email", Type: spec.ColumnTypeEmail}}},
+}}
+
+func TestReadSchemaDiffSourceFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := path.Join(dir, "old.xata")
+	require.NoError(t, ioutil.WriteFile(filename, FormatSchemaDSL(diffSourceSchema), 0644))
+
+	schema, name, err := readSchemaDiffSource(schemaDiffSource{schemaDiffFile, filename}, SettingsJSON, "xata/schema.json")
+	require.NoError(t, err)
+	require.Equal(t, filename, name)
+	require.Equal(t, diffSourceSchema, schema)
+
+	_, _, err = readSchemaDiffSource(schemaDiffSource{schemaDiffFile, path.Join(dir, "old.txt")}, SettingsJSON, "xata/schema.json")
+	require.EqualError(t, err, "Can't tell the format of "+path.Join(dir, "old.txt")+", the extension must be .json, .yaml or .xata")
+}
+
+func TestReadSchemaDiffSourceGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	require.NoError(t, os.Mkdir(path.Join(dir, "xata"), 0755))
+	data, err := marshalSchema(SettingsYAML, diffSourceSchema)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, "xata", "schema.yaml"), data, 0644))
+	git("add", "-A")
+	git("commit", "-q", "-m", "schema")
+	// the working copy differs, the revision is what gets compared
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, "xata", "schema.yaml"), []byte("tables: []\n"), 0644))
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	schema, name, err := readSchemaDiffSource(schemaDiffSource{schemaDiffGit, "HEAD"}, SettingsYAML, "xata/schema.yaml")
+	require.NoError(t, err)
+	require.Equal(t, "xata/schema.yaml@HEAD", name)
+	require.Equal(t, diffSourceSchema, schema)
+
+	_, _, err = readSchemaDiffSource(schemaDiffSource{schemaDiffGit, "HEAD"}, SettingsYAML, "xata/missing.yaml")
+	require.Error(t, err)
+}
@@ -0,0 +1,528 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/xataio/cli/client/spec"
+)
+
+// The .xata schema DSL is a compact alternative to the JSON and YAML schema
+// files. A file contains an optional format_version statement followed by
+// table blocks:
+//
+//	format_version "1.0"
+//
+//	table users {
+//	    email   email { unique: true }  // Email of the user
+//	    team    link { table: teams }
+//	    address {
+//	        zipcode int
+//	    }
+//	}
+//
+// A `//` comment on the same line as a column becomes its description,
+// unless the column sets one explicitly. Other column options are
+// `required`, `unique`, `default`, `table` (for links) and `renamedFrom`.
+//
+// Object columns take options in a block before their columns, e.g.
+// `address object { required: true } { ... }`.
+//
+// Table and column names can be quoted, e.g. `"2022" int`, for names that
+// would otherwise read as something else.
+//
+// Renames are declared with `table members renamedFrom users { ... }` and
+// with the `renamedFrom: old_name` column option.
+
+type dslTokenKind int
+
+const (
+	dslEOF dslTokenKind = iota
+	dslIdent
+	dslString
+	dslNumber
+	dslLBrace
+	dslRBrace
+	dslColon
+	dslComma
+	dslComment
+)
+
+func (k dslTokenKind) String() string {
+	switch k {
+	case dslEOF:
+		return "end of file"
+	case dslIdent:
+		return "identifier"
+	case dslString:
+		return "string"
+	case dslNumber:
+		return "number"
+	case dslLBrace:
+		return "`{`"
+	case dslRBrace:
+		return "`}`"
+	case dslColon:
+		return "`:`"
+	case dslComma:
+		return "`,`"
+	case dslComment:
+		return "comment"
+	}
+	return "unknown token"
+}
+
+type dslToken struct {
+	kind  dslTokenKind
+	value string
+	line  int
+	col   int
+}
+
+func (t dslToken) describe() string {
+	switch t.kind {
+	case dslIdent, dslNumber:
+		return fmt.Sprintf("%s `%s`", t.kind, t.value)
+	case dslString:
+		return fmt.Sprintf("string %q", t.value)
+	}
+	return t.kind.String()
+}
+
+// DSLError is returned when a .xata schema file can't be parsed.
+type DSLError struct {
+	Filename string
+	Line     int
+	Col      int
+	Message  string
+}
+
+func (e DSLError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Filename, e.Line, e.Col, e.Message)
+}
+
+type dslLexer struct {
+	filename string
+	src      []rune
+	pos      int
+	line     int
+	col      int
+}
+
+func (l *dslLexer) errorf(line, col int, format string, args ...interface{}) error {
+	return DSLError{Filename: l.filename, Line: line, Col: col, Message: fmt.Sprintf(format, args...)}
+}
+
+func (l *dslLexer) peek() rune {
+	if l.pos >= len(l.src) {
+		return 0
+	}
+	return l.src[l.pos]
+}
+
+func (l *dslLexer) advance() rune {
+	r := l.src[l.pos]
+	l.pos++
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+func isDSLIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_' || r == '-' || r == '~'
+}
+
+// isDSLNumber returns true if the word is a number. It has to start with a
+// digit, after an optional sign, so that names like `inf` or `nan` stay
+// identifiers.
+func isDSLNumber(word string) bool {
+	digits := strings.TrimLeft(word, "+-")
+	if len(word)-len(digits) > 1 || digits == "" {
+		return false
+	}
+	if !unicode.IsDigit(rune(digits[0])) && digits[0] != '.' {
+		return false
+	}
+	_, err := strconv.ParseFloat(word, 64)
+	return err == nil
+}
+
+func lexDSL(filename string, src []byte) ([]dslToken, error) {
+	l := &dslLexer{filename: filename, src: []rune(string(src)), line: 1, col: 1}
+	tokens := []dslToken{}
+	for {
+		for l.pos < len(l.src) && unicode.IsSpace(l.peek()) {
+			l.advance()
+		}
+		line, col := l.line, l.col
+		if l.pos >= len(l.src) {
+			tokens = append(tokens, dslToken{kind: dslEOF, line: line, col: col})
+			return tokens, nil
+		}
+
+		r := l.peek()
+		switch {
+		case r == '{':
+			l.advance()
+			tokens = append(tokens, dslToken{kind: dslLBrace, value: "{", line: line, col: col})
+		case r == '}':
+			l.advance()
+			tokens = append(tokens, dslToken{kind: dslRBrace, value: "}", line: line, col: col})
+		case r == ':':
+			l.advance()
+			tokens = append(tokens, dslToken{kind: dslColon, value: ":", line: line, col: col})
+		case r == ',':
+			l.advance()
+			tokens = append(tokens, dslToken{kind: dslComma, value: ",", line: line, col: col})
+		case r == '/':
+			l.advance()
+			if l.peek() != '/' {
+				return nil, l.errorf(line, col, "unexpected character `/`, comments start with `//`")
+			}
+			l.advance()
+			start := l.pos
+			for l.pos < len(l.src) && l.peek() != '\n' {
+				l.advance()
+			}
+			text := strings.TrimSpace(string(l.src[start:l.pos]))
+			tokens = append(tokens, dslToken{kind: dslComment, value: text, line: line, col: col})
+		case r == '"':
+			start := l.pos
+			l.advance()
+			for {
+				if l.pos >= len(l.src) || l.peek() == '\n' {
+					return nil, l.errorf(line, col, "unterminated string")
+				}
+				c := l.advance()
+				if c == '\\' && l.pos < len(l.src) {
+					l.advance()
+					continue
+				}
+				if c == '"' {
+					break
+				}
+			}
+			value, err := strconv.Unquote(string(l.src[start:l.pos]))
+			if err != nil {
+				return nil, l.errorf(line, col, "invalid string: %s", err)
+			}
+			tokens = append(tokens, dslToken{kind: dslString, value: value, line: line, col: col})
+		case isDSLIdentRune(r):
+			// identifiers may start with a digit, so numbers are told apart
+			// only once the whole word has been read
+			start := l.pos
+			for l.pos < len(l.src) && (isDSLIdentRune(l.peek()) || l.peek() == '.') {
+				l.advance()
+			}
+			word := string(l.src[start:l.pos])
+			kind := dslIdent
+			if isDSLNumber(word) {
+				kind = dslNumber
+			} else if strings.Contains(word, ".") {
+				return nil, l.errorf(line, col, "unexpected `%s`", word)
+			}
+			tokens = append(tokens, dslToken{kind: kind, value: word, line: line, col: col})
+		default:
+			return nil, l.errorf(line, col, "unexpected character %q", r)
+		}
+	}
+}
+
+type dslParser struct {
+	filename string
+	tokens   []dslToken
+	pos      int
+	// line of the last consumed token, used to find trailing comments
+	lastLine int
+}
+
+// ParseSchemaDSL parses the contents of a .xata schema file. The filename is
+// only used in error messages.
+func ParseSchemaDSL(filename string, src []byte) (spec.Schema, error) {
+	tokens, err := lexDSL(filename, src)
+	if err != nil {
+		return spec.Schema{}, err
+	}
+	p := &dslParser{filename: filename, tokens: tokens}
+	return p.parseSchema()
+}
+
+func (p *dslParser) errorf(tok dslToken, format string, args ...interface{}) error {
+	return DSLError{Filename: p.filename, Line: tok.line, Col: tok.col, Message: fmt.Sprintf(format, args...)}
+}
+
+// peekIndex returns the index of the next token that is not a comment.
+func (p *dslParser) peekIndex() int {
+	i := p.pos
+	for p.tokens[i].kind == dslComment {
+		i++
+	}
+	return i
+}
+
+func (p *dslParser) peek() dslToken {
+	return p.tokens[p.peekIndex()]
+}
+
+func (p *dslParser) next() dslToken {
+	i := p.peekIndex()
+	tok := p.tokens[i]
+	if tok.kind != dslEOF {
+		i++
+	}
+	p.pos = i
+	p.lastLine = tok.line
+	return tok
+}
+
+func (p *dslParser) expect(kind dslTokenKind, what string) (dslToken, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		return tok, p.errorf(tok, "expected %s, found %s", what, tok.describe())
+	}
+	return tok, nil
+}
+
+// trailingComment consumes and returns a comment placed on the same line as
+// the last consumed token.
+func (p *dslParser) trailingComment() string {
+	tok := p.tokens[p.pos]
+	if tok.kind == dslComment && tok.line == p.lastLine {
+		p.pos++
+		return tok.value
+	}
+	return ""
+}
+
+func (p *dslParser) parseSchema() (spec.Schema, error) {
+	schema := spec.Schema{Tables: []spec.Table{}}
+	seenVersion := false
+	for {
+		tok := p.next()
+		switch {
+		case tok.kind == dslEOF:
+			return schema, nil
+		case tok.kind == dslIdent && tok.value == "format_version":
+			if seenVersion {
+				return spec.Schema{}, p.errorf(tok, "format_version is declared more than once")
+			}
+			seenVersion = true
+			version := p.next()
+			if version.kind != dslString && version.kind != dslNumber {
+				return spec.Schema{}, p.errorf(version, "expected format version string, found %s", version.describe())
+			}
+			schema.FormatVersion = version.value
+		case tok.kind == dslIdent && tok.value == "table":
+			table, err := p.parseTable()
+			if err != nil {
+				return spec.Schema{}, err
+			}
+			schema.Tables = append(schema.Tables, table)
+		default:
+			return spec.Schema{}, p.errorf(tok, "expected `table` or `format_version`, found %s", tok.describe())
+		}
+	}
+}
+
+func (p *dslParser) parseTable() (spec.Table, error) {
+	name := p.next()
+	if name.kind != dslIdent && name.kind != dslString {
+		return spec.Table{}, p.errorf(name, "expected table name, found %s", name.describe())
+	}
+	table := spec.Table{Name: name.value}
+
+	if tok := p.peek(); tok.kind == dslIdent && tok.value == "renamedFrom" {
+		p.next()
+		oldName := p.next()
+		if oldName.kind != dslIdent && oldName.kind != dslString {
+			return spec.Table{}, p.errorf(oldName, "expected the old table name after `renamedFrom`, found %s", oldName.describe())
+		}
+		table.RenamedFrom = oldName.value
+	}
+
+	if _, err := p.expect(dslLBrace, "`{` after table name"); err != nil {
+		return spec.Table{}, err
+	}
+	p.trailingComment()
+
+	columns, err := p.parseColumns()
+	if err != nil {
+		return spec.Table{}, err
+	}
+	table.Columns = columns
+	return table, nil
+}
+
+// parseColumns parses column definitions up to and including the closing brace.
+func (p *dslParser) parseColumns() ([]spec.Column, error) {
+	columns := []spec.Column{}
+	for {
+		tok := p.peek()
+		switch tok.kind {
+		case dslRBrace:
+			p.next()
+			return columns, nil
+		case dslIdent, dslString:
+			column, err := p.parseColumn()
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, column)
+		default:
+			return nil, p.errorf(tok, "expected column name or `}`, found %s", tok.describe())
+		}
+	}
+}
+
+func (p *dslParser) parseColumn() (spec.Column, error) {
+	name := p.next()
+	column := spec.Column{Name: name.value}
+
+	tok := p.next()
+	switch tok.kind {
+	case dslLBrace:
+		// `name { ... }` is a shorthand for an object column
+		column.Type = spec.ColumnTypeObject
+	case dslIdent:
+		column.Type = spec.ColumnTypeFromString(tok.value)
+		if column.Type == 0 {
+			return spec.Column{}, p.errorf(tok, "unknown column type `%s`", tok.value)
+		}
+		if column.Type == spec.ColumnTypeObject {
+			if _, err := p.expect(dslLBrace, "`{` after object column"); err != nil {
+				return spec.Column{}, err
+			}
+			// `name object { key: value } { ... }` sets options on the
+			// object before its columns
+			if p.atOption() {
+				if err := p.parseOptions(&column); err != nil {
+					return spec.Column{}, err
+				}
+				if _, err := p.expect(dslLBrace, "`{` before the columns of the object"); err != nil {
+					return spec.Column{}, err
+				}
+			}
+		}
+	default:
+		return spec.Column{}, p.errorf(tok, "expected type of column `%s`, found %s", column.Name, tok.describe())
+	}
+
+	if column.Type == spec.ColumnTypeObject {
+		if comment := p.trailingComment(); comment != "" && column.Description == "" {
+			column.Description = comment
+		}
+		columns, err := p.parseColumns()
+		if err != nil {
+			return spec.Column{}, err
+		}
+		column.Columns = columns
+		return column, nil
+	}
+
+	if p.peek().kind == dslLBrace && p.peek().line == p.lastLine {
+		p.next()
+		if err := p.parseOptions(&column); err != nil {
+			return spec.Column{}, err
+		}
+	}
+
+	if comment := p.trailingComment(); comment != "" && column.Description == "" {
+		column.Description = comment
+	}
+
+	if column.Type == spec.ColumnTypeLink && column.Link == nil {
+		return spec.Column{}, p.errorf(name, "link column `%s` requires a `table` option", column.Name)
+	}
+	return column, nil
+}
+
+// atOption reports whether the next tokens are a `key:` pair.
+func (p *dslParser) atOption() bool {
+	pos, lastLine := p.pos, p.lastLine
+	defer func() { p.pos, p.lastLine = pos, lastLine }()
+	return p.next().kind == dslIdent && p.next().kind == dslColon
+}
+
+// parseOptions parses `key: value` pairs up to and including the closing brace.
+func (p *dslParser) parseOptions(column *spec.Column) error {
+	for {
+		key := p.next()
+		if key.kind == dslRBrace {
+			return nil
+		}
+		if key.kind != dslIdent {
+			return p.errorf(key, "expected option name, found %s", key.describe())
+		}
+		if _, err := p.expect(dslColon, "`:` after option name"); err != nil {
+			return err
+		}
+		value := p.next()
+
+		switch key.value {
+		case "required", "unique":
+			b, err := p.boolValue(value, key.value)
+			if err != nil {
+				return err
+			}
+			if key.value == "required" {
+				column.Required = b
+			} else {
+				column.Unique = b
+			}
+		case "description":
+			if value.kind != dslString {
+				return p.errorf(value, "option `description` must be a string, found %s", value.describe())
+			}
+			column.Description = value.value
+		case "default":
+			isBool := value.kind == dslIdent && (value.value == "true" || value.value == "false")
+			if value.kind != dslString && value.kind != dslNumber && !isBool {
+				return p.errorf(value, "option `default` must be a string, number or boolean, found %s", value.describe())
+			}
+			column.DefaultValue = value.value
+		case "renamedFrom":
+			if value.kind != dslIdent && value.kind != dslString {
+				return p.errorf(value, "option `renamedFrom` must be a column name, found %s", value.describe())
+			}
+			column.RenamedFrom = value.value
+		case "table":
+			if column.Type != spec.ColumnTypeLink {
+				return p.errorf(key, "option `table` is only valid on link columns")
+			}
+			if value.kind != dslIdent && value.kind != dslString {
+				return p.errorf(value, "option `table` must be a table name, found %s", value.describe())
+			}
+			column.Link = &spec.ColumnLink{Table: value.value}
+		default:
+			return p.errorf(key, "unknown column option `%s`", key.value)
+		}
+
+		sep := p.next()
+		switch sep.kind {
+		case dslComma:
+			continue
+		case dslRBrace:
+			return nil
+		default:
+			return p.errorf(sep, "expected `,` or `}` after option value, found %s", sep.describe())
+		}
+	}
+}
+
+func (p *dslParser) boolValue(tok dslToken, option string) (bool, error) {
+	if tok.kind == dslIdent {
+		switch tok.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, p.errorf(tok, "option `%s` must be true or false, found %s", option, tok.describe())
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xataio/cli/client/spec"
+)
+
+const dslIndent = "    "
+
+// FormatSchemaDSL writes a schema in the canonical .xata DSL layout: column
+// names and trailing comments are aligned within each run of consecutive
+// columns, and descriptions that fit on one line are written as trailing
+// comments. Formatting the result of ParseSchemaDSL on the output gives back
+// the same bytes.
+func FormatSchemaDSL(schema spec.Schema) []byte {
+	var buf bytes.Buffer
+	if schema.FormatVersion != "" {
+		fmt.Fprintf(&buf, "format_version %s\n", strconv.Quote(schema.FormatVersion))
+	}
+	for _, table := range schema.Tables {
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		header := "table " + dslName(table.Name)
+		if table.RenamedFrom != "" {
+			header += " renamedFrom " + dslName(table.RenamedFrom)
+		}
+		if len(table.Columns) == 0 {
+			fmt.Fprintf(&buf, "%s {}\n", header)
+			continue
+		}
+		fmt.Fprintf(&buf, "%s {\n", header)
+		writeDSLColumns(&buf, table.Columns, 1)
+		buf.WriteString("}\n")
+	}
+	return buf.Bytes()
+}
+
+// dslLine is a column definition split in the parts that get aligned.
+type dslLine struct {
+	name    string
+	rest    string
+	comment string
+}
+
+func writeDSLColumns(buf *bytes.Buffer, columns []spec.Column, depth int) {
+	indent := strings.Repeat(dslIndent, depth)
+
+	run := []dslLine{}
+	flush := func() {
+		writeDSLRun(buf, indent, run)
+		run = run[:0]
+	}
+
+	for _, column := range columns {
+		if column.Type != spec.ColumnTypeObject {
+			run = append(run, dslColumnLine(column))
+			continue
+		}
+
+		flush()
+		line := dslColumnLine(column)
+		if line.rest == column.Type.String() {
+			// without options the `object` keyword can be left out
+			fmt.Fprintf(buf, "%s%s {", indent, line.name)
+		} else {
+			fmt.Fprintf(buf, "%s%s %s {", indent, line.name, line.rest)
+		}
+		if line.comment != "" {
+			fmt.Fprintf(buf, " // %s", line.comment)
+		}
+		buf.WriteString("\n")
+		writeDSLColumns(buf, column.Columns, depth+1)
+		fmt.Fprintf(buf, "%s}\n", indent)
+	}
+	flush()
+}
+
+func writeDSLRun(buf *bytes.Buffer, indent string, run []dslLine) {
+	nameWidth, restWidth := 0, 0
+	for _, line := range run {
+		if width := utf8.RuneCountInString(line.name); width > nameWidth {
+			nameWidth = width
+		}
+	}
+	for _, line := range run {
+		if line.comment == "" {
+			continue
+		}
+		if width := nameWidth + 1 + utf8.RuneCountInString(line.rest); width > restWidth {
+			restWidth = width
+		}
+	}
+
+	for _, line := range run {
+		text := fmt.Sprintf("%-*s %s", nameWidth, line.name, line.rest)
+		if line.comment != "" {
+			text = fmt.Sprintf("%-*s  // %s", restWidth, text, line.comment)
+		}
+		buf.WriteString(indent)
+		buf.WriteString(text)
+		buf.WriteString("\n")
+	}
+}
+
+func dslColumnLine(column spec.Column) dslLine {
+	line := dslLine{name: dslName(column.Name), rest: column.Type.String()}
+
+	// single line descriptions are kept as trailing comments, everything
+	// else has to be quoted to survive a round trip
+	description := column.Description
+	if description != "" && dslCommentText(description) == description {
+		line.comment = description
+		description = ""
+	}
+
+	options := []string{}
+	if column.Required {
+		options = append(options, "required: true")
+	}
+	if column.Unique {
+		options = append(options, "unique: true")
+	}
+	if column.DefaultValue != "" {
+		options = append(options, "default: "+dslLiteral(column.DefaultValue))
+	}
+	if column.Link != nil {
+		options = append(options, "table: "+dslName(column.Link.Table))
+	}
+	if column.RenamedFrom != "" {
+		options = append(options, "renamedFrom: "+dslName(column.RenamedFrom))
+	}
+	if description != "" {
+		options = append(options, "description: "+strconv.Quote(description))
+	}
+	if len(options) > 0 {
+		line.rest += " { " + strings.Join(options, ", ") + " }"
+	}
+	return line
+}
+
+// dslCommentText returns how s reads back once written as a `//` comment.
+func dslCommentText(s string) string {
+	return strings.TrimSpace(strings.Join(strings.Fields(s), " "))
+}
+
+// dslLiteral writes numbers and booleans bare and quotes everything else,
+// including numbers the lexer wouldn't read back as one word, like `.5`.
+func dslLiteral(value string) string {
+	if value == "true" || value == "false" {
+		return value
+	}
+	if isDSLNumber(value) && isDSLIdentRune([]rune(value)[0]) && strings.IndexFunc(value, func(r rune) bool {
+		return !isDSLIdentRune(r) && r != '.'
+	}) < 0 {
+		return value
+	}
+	return strconv.Quote(value)
+}
+
+// dslName quotes names that wouldn't read back as a single identifier.
+func dslName(name string) string {
+	if spec.IsValidIdentifier(name) && !isDSLNumber(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
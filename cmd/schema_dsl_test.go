@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	_ "embed"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+//go:embed default_schema.xata
+var defaultSchemaDSL []byte
+
+func TestParseSchemaDSLDefaultSchema(t *testing.T) {
+	schema, err := ParseSchemaDSL("default_schema.xata", defaultSchemaDSL)
+	require.NoError(t, err)
+	require.Equal(t, spec.Schema{
+		FormatVersion: "1.0",
+		Tables: []spec.Table{
+			{
+				Name: "teams",
+				Columns: []spec.Column{
+					{Name: "name", Type: spec.ColumnTypeString, Required: true, Unique: true, Description: "Name of the team"},
+					{Name: "labels", Type: spec.ColumnTypeMultiple},
+					{Name: "owner", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "users"}},
+				},
+			},
+			{
+				Name: "users",
+				Columns: []spec.Column{
+					{Name: "email", Type: spec.ColumnTypeEmail, Required: true, Unique: true, Description: "Email of the user"},
+					{Name: "full_name", Type: spec.ColumnTypeString},
+					{Name: "street", Type: spec.ColumnTypeString},
+					{Name: "address", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+						{Name: "zipcode", Type: spec.ColumnTypeInt, Required: true, DefaultValue: "0"},
+					}},
+					{Name: "team", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "users"}},
+				},
+			},
+		},
+	}, schema)
+}
+
+func TestParseSchemaDSL(t *testing.T) {
+	src := `
+// leading comments are ignored
+table posts {
+    title  string { required: true, unique: true, description: "Title" } // ignored, description is set
+    body   text // The body
+    author link { table: "users" }
+    meta object { // Metadata
+        tags multiple
+    }
+    stats object { required: true, description: "Counters" } { // ignored, description is set
+        likes int
+    }
+}
+table users {}
+`
+	schema, err := ParseSchemaDSL("schema.xata", []byte(src))
+	require.NoError(t, err)
+	require.Equal(t, spec.Schema{
+		Tables: []spec.Table{
+			{
+				Name: "posts",
+				Columns: []spec.Column{
+					{Name: "title", Type: spec.ColumnTypeString, Required: true, Unique: true, Description: "Title"},
+					{Name: "body", Type: spec.ColumnTypeText, Description: "The body"},
+					{Name: "author", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "users"}},
+					{Name: "meta", Type: spec.ColumnTypeObject, Description: "Metadata", Columns: []spec.Column{
+						{Name: "tags", Type: spec.ColumnTypeMultiple},
+					}},
+					{Name: "stats", Type: spec.ColumnTypeObject, Required: true, Description: "Counters", Columns: []spec.Column{
+						{Name: "likes", Type: spec.ColumnTypeInt},
+					}},
+				},
+			},
+			{Name: "users", Columns: []spec.Column{}},
+		},
+	}, schema)
+}
+
+func TestParseSchemaDSLNumberLikeNames(t *testing.T) {
+	src := "table inf {\n  NaN int { default: -1.5 }\n  Infinity float { default: 0.5 }\n  e1 int { default: \"inf\" }\n}"
+	schema, err := ParseSchemaDSL("schema.xata", []byte(src))
+	require.NoError(t, err)
+	require.Equal(t, spec.Schema{
+		Tables: []spec.Table{
+			{Name: "inf", Columns: []spec.Column{
+				{Name: "NaN", Type: spec.ColumnTypeInt, DefaultValue: "-1.5"},
+				{Name: "Infinity", Type: spec.ColumnTypeFloat, DefaultValue: "0.5"},
+				{Name: "e1", Type: spec.ColumnTypeInt, DefaultValue: "inf"},
+			}},
+		},
+	}, schema)
+
+	reparsed, err := ParseSchemaDSL("schema.xata", FormatSchemaDSL(schema))
+	require.NoError(t, err)
+	require.Equal(t, schema, reparsed)
+}
+
+func TestParseSchemaDSLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{
+			name: "unknown type",
+			src:  "table t {\n  a strin\n}",
+			err:  "schema.xata:2:5: unknown column type `strin`",
+		},
+		{
+			name: "missing link table",
+			src:  "table t {\n  a link\n}",
+			err:  "schema.xata:2:3: link column `a` requires a `table` option",
+		},
+		{
+			name: "unknown option",
+			src:  "table t {\n  a int { min: 0 }\n}",
+			err:  "schema.xata:2:11: unknown column option `min`",
+		},
+		{
+			name: "bad default value",
+			src:  "table t {\n  a int { default: zero }\n}",
+			err:  "schema.xata:2:20: option `default` must be a string, number or boolean, found identifier `zero`",
+		},
+		{
+			name: "object option without columns",
+			src:  "table t {\n  a object { required: true }\n}",
+			err:  "schema.xata:3:1: expected `{` before the columns of the object, found `}`",
+		},
+		{
+			name: "bad option value",
+			src:  "table t {\n  a int { required: 1 }\n}",
+			err:  "schema.xata:2:21: option `required` must be true or false, found number `1`",
+		},
+		{
+			name: "unterminated table",
+			src:  "table t {\n  a int\n",
+			err:  "schema.xata:3:1: expected column name or `}`, found end of file",
+		},
+		{
+			name: "unterminated string",
+			src:  "format_version \"1.0\n",
+			err:  "schema.xata:1:16: unterminated string",
+		},
+		{
+			name: "unexpected statement",
+			src:  "tables t {}",
+			err:  "schema.xata:1:1: expected `table` or `format_version`, found identifier `tables`",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseSchemaDSL("schema.xata", []byte(test.src))
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestFormatSchemaDSL(t *testing.T) {
+	schema, err := ParseSchemaDSL("default_schema.xata", defaultSchemaDSL)
+	require.NoError(t, err)
+
+	expected := `format_version "1.0"
+
+table teams {
+    name   string { required: true, unique: true }  // Name of the team
+    labels multiple
+    owner  link { table: users }
+}
+
+table users {
+    email     email { required: true, unique: true }  // Email of the user
+    full_name string
+    street    string
+    address {
+        zipcode int { required: true, default: 0 }
+    }
+    team link { table: users }
+}
+`
+	require.Equal(t, expected, string(FormatSchemaDSL(schema)))
+}
+
+func TestFormatSchemaDSLRoundTrip(t *testing.T) {
+	schema := spec.Schema{
+		FormatVersion: "1.0",
+		Tables: []spec.Table{
+			{
+				Name: "posts",
+				Columns: []spec.Column{
+					{Name: "title", Type: spec.ColumnTypeString, Required: true, Description: "The title"},
+					{Name: "body", Type: spec.ColumnTypeText, Description: "Multi\nline \"quoted\""},
+					{Name: "author", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "users"}, Description: " padded "},
+					{Name: "meta", Type: spec.ColumnTypeObject, Description: "Metadata", Columns: []spec.Column{
+						{Name: "tags", Type: spec.ColumnTypeMultiple, Unique: true},
+						{Name: "nested", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+							{Name: "score", Type: spec.ColumnTypeFloat},
+						}},
+					}},
+					{Name: "address", Type: spec.ColumnTypeObject, Required: true, Unique: true, RenamedFrom: "location", Description: "Postal\naddress", Columns: []spec.Column{
+						{Name: "zip", Type: spec.ColumnTypeString},
+					}},
+					{Name: "stats", Type: spec.ColumnTypeObject, Required: true, Description: "Counters", Columns: []spec.Column{
+						{Name: "likes", Type: spec.ColumnTypeInt},
+					}},
+					{Name: "views", Type: spec.ColumnTypeInt, RenamedFrom: "view_count", DefaultValue: "-1"},
+					{Name: "status", Type: spec.ColumnTypeString, DefaultValue: "draft post"},
+					{Name: "draft", Type: spec.ColumnTypeBool, DefaultValue: "true"},
+				},
+			},
+			{Name: "empty", RenamedFrom: "blank", Columns: []spec.Column{}},
+			{Name: "2022 stats", Columns: []spec.Column{
+				{Name: "2022", Type: spec.ColumnTypeInt},
+				{Name: "per day", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+					{Name: "inf", Type: spec.ColumnTypeFloat},
+				}},
+			}},
+		},
+	}
+
+	first := FormatSchemaDSL(schema)
+	parsed, err := ParseSchemaDSL("schema.xata", first)
+	require.NoError(t, err)
+	require.Equal(t, schema, parsed)
+
+	second := FormatSchemaDSL(parsed)
+	require.Equal(t, string(first), string(second))
+}
@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/urfave/cli/v2"
+)
+
+// getMigrationHistory returns the migrations of a branch, newest first,
+// starting from startFromID (or the latest migration if empty). With follow,
+// it continues into the branch the schema was copied from, so the result goes
+// back to the origin of the database.
+func getMigrationHistory(ctx context.Context, client *spec.ClientWithResponses, dbName, branchName,
+	startFromID string, follow bool) ([]spec.BranchMigration, error) {
+	res := []spec.BranchMigration{}
+	err := walkMigrationHistory(ctx, client, dbName, branchName, startFromID, follow,
+		func(migration *spec.BranchMigration, origin *spec.StartedFromMetadata) bool {
+			if migration != nil {
+				res = append(res, *migration)
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// replayMigrations builds the schema that results from applying the
+// migrations, given newest first like getMigrationHistory returns them, to
+// an empty schema. Failed migrations are skipped.
+func replayMigrations(migrations []spec.BranchMigration) spec.Schema {
+	schema := spec.Schema{FormatVersion: schemaVersion, Tables: []spec.Table{}}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if migrations[i].Status == "failed" {
+			continue
+		}
+		schema = applyMigration(schema, migrations[i])
+	}
+	return schema
+}
+
+// applyMigration returns the schema after the migration.
+func applyMigration(schema spec.Schema, migration spec.BranchMigration) spec.Schema {
+	tables := tablesByName(schema)
+	order := make([]string, 0, len(schema.Tables))
+	for _, table := range schema.Tables {
+		order = append(order, table.Name)
+	}
+
+	if migration.RemovedTables != nil {
+		for _, name := range *migration.RemovedTables {
+			delete(tables, name)
+			order = removeName(order, name)
+		}
+	}
+	if migration.RenamedTables != nil {
+		for _, rename := range *migration.RenamedTables {
+			table, ok := tables[rename.OldName]
+			if !ok {
+				continue
+			}
+			delete(tables, rename.OldName)
+			table.Name = rename.NewName
+			tables[rename.NewName] = table
+			for i, name := range order {
+				if name == rename.OldName {
+					order[i] = rename.NewName
+				}
+			}
+		}
+	}
+	if migration.NewTables != nil {
+		for _, name := range sortedTableNames(migration.NewTables.AdditionalProperties) {
+			table := migration.NewTables.AdditionalProperties[name]
+			table.Name = name
+			if _, exists := tables[name]; !exists {
+				order = append(order, name)
+			}
+			tables[name] = table
+		}
+	}
+	if migration.TableMigrations != nil {
+		for name, tableMigration := range migration.TableMigrations.AdditionalProperties {
+			table, ok := tables[name]
+			if !ok {
+				continue
+			}
+			tables[name] = applyTableMigration(table, tableMigration)
+		}
+	}
+
+	schema.Tables = make([]spec.Table, 0, len(tables))
+	for _, name := range reorderNames(order, migration.NewTableOrder) {
+		schema.Tables = append(schema.Tables, tables[name])
+	}
+	return schema
+}
+
+func applyTableMigration(table spec.Table, migration spec.TableMigration) spec.Table {
+	columns := map[string]spec.Column{}
+	order := []string{}
+	for _, column := range table.Columns {
+		columns[column.Name] = column
+		order = append(order, column.Name)
+	}
+
+	if migration.RemovedColumns != nil {
+		for _, name := range *migration.RemovedColumns {
+			delete(columns, name)
+			order = removeName(order, name)
+		}
+	}
+	if migration.ModifiedColumns != nil {
+		for _, modified := range *migration.ModifiedColumns {
+			if _, ok := columns[modified.Old.Name]; !ok {
+				continue
+			}
+			delete(columns, modified.Old.Name)
+			columns[modified.New.Name] = modified.New
+			for i, name := range order {
+				if name == modified.Old.Name {
+					order[i] = modified.New.Name
+				}
+			}
+		}
+	}
+	if migration.NewColumns != nil {
+		for _, name := range sortedColumnNames(migration.NewColumns.AdditionalProperties) {
+			column := migration.NewColumns.AdditionalProperties[name]
+			column.Name = name
+			if _, exists := columns[name]; !exists {
+				order = append(order, name)
+			}
+			columns[name] = column
+		}
+	}
+
+	table.Columns = make([]spec.Column, 0, len(columns))
+	for _, name := range reorderNames(order, migration.NewColumnOrder) {
+		table.Columns = append(table.Columns, columns[name])
+	}
+	return table
+}
+
+// reorderNames sorts names in the given order. Names missing from the order
+// keep their relative position at the end.
+func reorderNames(names, order []string) []string {
+	present := map[string]bool{}
+	for _, name := range names {
+		present[name] = true
+	}
+	res := make([]string, 0, len(names))
+	placed := map[string]bool{}
+	for _, name := range order {
+		if present[name] && !placed[name] {
+			res = append(res, name)
+			placed[name] = true
+		}
+	}
+	for _, name := range names {
+		if !placed[name] {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+func removeName(names []string, name string) []string {
+	res := make([]string, 0, len(names))
+	for _, n := range names {
+		if n != name {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// findMigrationIndex returns the index of the migration with the given ID, or
+// of the newest migration made from the given git revision (a prefix of the
+// sha is enough). It returns -1 if there is none.
+func findMigrationIndex(migrations []spec.BranchMigration, id, gitRevision string) int {
+	for i, migration := range migrations {
+		switch {
+		case id != "" && migration.Id != nil && *migration.Id == id:
+			return i
+		case gitRevision != "" && migration.LastGitRevision != nil &&
+			strings.HasPrefix(*migration.LastGitRevision, gitRevision):
+			return i
+		}
+	}
+	return -1
+}
+
+// SchemaShowCommand prints the schema of the branch as it was after a given
+// migration, by replaying the migration history.
+func SchemaShowCommand(c *cli.Context) error {
+	if c.IsSet("at") && c.IsSet("at-git") {
+		return fmt.Errorf("Only one of --at and --at-git can be used.")
+	}
+
+	settings, err := ReadSettings(c.String("dir"))
+	if err != nil {
+		return err
+	}
+	format := settings.SchemaFileFormat
+	if c.IsSet("format") {
+		format = c.String("format")
+		if format != SettingsJSON && format != SettingsYAML && format != SettingsXata {
+			return fmt.Errorf("The format must be either `json`, `yaml` or `xata`")
+		}
+	}
+
+	dbName, _, branch, err := getDBNameAndBranch(c)
+	if err != nil {
+		return err
+	}
+	if c.IsSet("branch") {
+		branch = c.String("branch")
+	}
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+
+	migrations, err := getMigrationHistory(c.Context, client, dbName, branch, "", true)
+	if err != nil {
+		return err
+	}
+	if c.IsSet("at") || c.IsSet("at-git") {
+		i := findMigrationIndex(migrations, c.String("at"), c.String("at-git"))
+		if i < 0 {
+			if c.IsSet("at") {
+				return fmt.Errorf("Migration [%s] isn't in the history of branch [%s:%s]", c.String("at"), dbName, branch)
+			}
+			return fmt.Errorf("No migration of branch [%s:%s] was made from git revision [%s]", dbName, branch, c.String("at-git"))
+		}
+		migrations = migrations[i:]
+	}
+	schema := replayMigrations(migrations)
+
+	if c.Bool("json") {
+		out, err := json.Marshal(schema)
+		if err != nil {
+			return err
+		}
+		return printJSON(c, out)
+	}
+	out, err := marshalSchema(format, schema)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(out))
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestReplayMigrations(t *testing.T) {
+	var v1 spec.Schema
+	require.NoError(t, json.Unmarshal(defaultSchema, &v1))
+
+	v2, err := ParseSchemaDSL("v2.xata", []byte(`format_version "1.0"
+
+table people renamedFrom users {
+    name  string { renamedFrom: full_name }
+    email email
+    age   int
+    address {
+        street  string
+        country string
+    }
+    team link { table: teams }
+}
+
+table teams {
+    name   string { unique: true }
+    owner  link { table: people }
+}
+`))
+	require.NoError(t, err)
+	v3 := spec.Schema{FormatVersion: "1.0", Tables: []spec.Table{
+		{Name: "teams", Columns: []spec.Column{{Name: "name", Type: spec.ColumnTypeText}}},
+	}}
+
+	m1 := PlanMigration(spec.Schema{}, v1)
+	m1.Id = stringPtr("mig_1")
+	m2 := PlanMigration(v1, v2)
+	m2.Id = stringPtr("mig_2")
+	m2.LastGitRevision = stringPtr("4f2a9c0d1e")
+	failed := PlanMigration(v2, spec.Schema{})
+	failed.Status = "failed"
+	m3 := PlanMigration(v2, v3)
+	m3.Id = stringPtr("mig_3")
+
+	// newest first, like the history API returns them
+	history := []spec.BranchMigration{m3, failed, m2, m1}
+
+	require.True(t, schemasEqual(v3, replayMigrations(history)))
+
+	i := findMigrationIndex(history, "mig_2", "")
+	require.Equal(t, 2, i)
+	require.True(t, schemasEqual(stripRenameHints(v2), replayMigrations(history[i:])))
+
+	require.Equal(t, 2, findMigrationIndex(history, "", "4f2a9c"))
+	require.True(t, schemasEqual(v1, replayMigrations(history[3:])))
+	require.Equal(t, -1, findMigrationIndex(history, "mig_4", ""))
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
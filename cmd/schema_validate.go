@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli/v2"
+)
+
+// SchemaDiagnostic is a problem found in a schema, located by its path in
+// the schema document, e.g. `tables[1].columns[3]`.
+type SchemaDiagnostic struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (d SchemaDiagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Path, d.Message)
+}
+
+// schemaErrorDiagnostic turns an error reading the schema file into a
+// diagnostic. Syntax errors in .xata files are located by `line:column`.
+func schemaErrorDiagnostic(err error) SchemaDiagnostic {
+	var dslErr DSLError
+	if errors.As(err, &dslErr) {
+		return SchemaDiagnostic{Path: fmt.Sprintf("%d:%d", dslErr.Line, dslErr.Col), Message: dslErr.Message}
+	}
+	return SchemaDiagnostic{Message: err.Error()}
+}
+
+// uniqueColumnTypes are the column types that support the unique constraint.
+var uniqueColumnTypes = map[spec.ColumnType]bool{
+	spec.ColumnTypeString: true,
+	spec.ColumnTypeEmail:  true,
+	spec.ColumnTypeInt:    true,
+	spec.ColumnTypeFloat:  true,
+}
+
+// ValidateSchema checks a schema without contacting the server, and returns
+// the problems it finds. An empty result means the schema is valid.
+func ValidateSchema(schema spec.Schema) []SchemaDiagnostic {
+	diagnostics := []SchemaDiagnostic{}
+	report := func(path, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, SchemaDiagnostic{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch schema.FormatVersion {
+	case schemaVersion:
+	case "":
+		report("formatVersion", "formatVersion is missing, expected %q", schemaVersion)
+	default:
+		report("formatVersion", "unsupported formatVersion %q, expected %q", schema.FormatVersion, schemaVersion)
+	}
+
+	tableNames := map[string]bool{}
+	for _, table := range schema.Tables {
+		tableNames[table.Name] = true
+	}
+
+	seenTables := map[string]bool{}
+	for i, table := range schema.Tables {
+		path := fmt.Sprintf("tables[%d]", i)
+		if !spec.IsValidIdentifier(table.Name) {
+			report(path, "invalid table name %q", table.Name)
+		}
+		if seenTables[table.Name] {
+			report(path, "duplicate table name %q", table.Name)
+		}
+		seenTables[table.Name] = true
+		if table.RenamedFrom != "" && tableNames[table.RenamedFrom] {
+			report(path, "table %q is renamed from %q, which is still in the schema", table.Name, table.RenamedFrom)
+		}
+
+		validateColumns(path, table.Columns, tableNames, report)
+	}
+	return diagnostics
+}
+
+func validateColumns(parentPath string, columns []spec.Column, tableNames map[string]bool,
+	report func(path, format string, args ...interface{})) {
+
+	seen := map[string]bool{}
+	for i, column := range columns {
+		path := fmt.Sprintf("%s.columns[%d]", parentPath, i)
+		if !spec.IsValidIdentifier(column.Name) {
+			report(path, "invalid column name %q", column.Name)
+		}
+		if seen[column.Name] {
+			report(path, "duplicate column name %q", column.Name)
+		}
+		seen[column.Name] = true
+
+		if column.RenamedFrom != "" && strings.Contains(parentPath, ".columns[") {
+			report(path, "column %q is nested in an object, so it can't be renamed with renamedFrom", column.Name)
+		}
+		if column.RenamedFrom != "" && columnIndex(columns, column.RenamedFrom) >= 0 {
+			report(path, "column %q is renamed from %q, which is still in the table", column.Name, column.RenamedFrom)
+		}
+		if column.Type == 0 {
+			report(path, "column %q has no type", column.Name)
+			continue
+		}
+		if column.Unique && !uniqueColumnTypes[column.Type] {
+			report(path, "column %q of type %s can't be unique", column.Name, column.Type)
+		}
+
+		switch column.Type {
+		case spec.ColumnTypeLink:
+			switch {
+			case column.Link == nil || column.Link.Table == "":
+				report(path, "link column %q doesn't say which table it links to", column.Name)
+			case !tableNames[column.Link.Table]:
+				report(path, "link column %q references table %q that doesn't exist", column.Name, column.Link.Table)
+			}
+		case spec.ColumnTypeObject:
+			if len(column.Columns) == 0 {
+				report(path, "object column %q has no columns", column.Name)
+			}
+			validateColumns(path, column.Columns, tableNames, report)
+		default:
+			if len(column.Columns) > 0 {
+				report(path, "column %q of type %s can't have nested columns", column.Name, column.Type)
+			}
+		}
+	}
+}
+
+// SchemaValidateCommand validates the local schema file and exits with a
+// non-zero code if it has problems.
+func SchemaValidateCommand(c *cli.Context) error {
+	dir := c.String("dir")
+	var diagnostics []SchemaDiagnostic
+	schema, schemaFile, err := readSchemaFile(dir)
+	if err != nil {
+		if !c.Bool("json") {
+			return err
+		}
+		// scripts reading the JSON output need to see unreadable files too
+		schemaFile = path.Join(dir, settingsFilename)
+		if settings, settingsErr := ReadSettings(dir); settingsErr == nil {
+			schemaFile = SchemaFilePath(dir, settings)
+		}
+		diagnostics = []SchemaDiagnostic{schemaErrorDiagnostic(err)}
+	} else {
+		diagnostics = ValidateSchema(schema)
+	}
+
+	if c.Bool("json") {
+		out, err := json.Marshal(struct {
+			File        string             `json:"file"`
+			Valid       bool               `json:"valid"`
+			Diagnostics []SchemaDiagnostic `json:"diagnostics"`
+		}{schemaFile, len(diagnostics) == 0, diagnostics})
+		if err != nil {
+			return err
+		}
+		if err := printJSON(c, out); err != nil {
+			return err
+		}
+	} else {
+		red := color.New(color.FgRed)
+		if c.Bool("nocolor") {
+			red.DisableColor()
+		}
+		for _, diagnostic := range diagnostics {
+			fmt.Printf("%s: ", schemaFile)
+			red.Printf("%s\n", diagnostic)
+		}
+		if len(diagnostics) == 0 {
+			fmt.Printf("%s is valid\n", schemaFile)
+		}
+	}
+
+	if len(diagnostics) > 0 {
+		if c.Bool("json") {
+			return cli.Exit("", 1)
+		}
+		return cli.Exit(fmt.Sprintf("Found %d problem(s) in %s", len(diagnostics), schemaFile), 1)
+	}
+	return nil
+}
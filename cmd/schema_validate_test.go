@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func TestValidateSchemaDefaultSchema(t *testing.T) {
+	var schema spec.Schema
+	err := json.Unmarshal(defaultSchema, &schema)
+	require.NoError(t, err)
+
+	require.Empty(t, ValidateSchema(schema))
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema := spec.Schema{
+		FormatVersion: "2.0",
+		Tables: []spec.Table{
+			{
+				Name: "users",
+				Columns: []spec.Column{
+					{Name: "name", Type: spec.ColumnTypeString},
+					{Name: "name", Type: spec.ColumnTypeText, Unique: true},
+					{Name: "team", Type: spec.ColumnTypeLink, Link: &spec.ColumnLink{Table: "teams"}},
+					{Name: "address", Type: spec.ColumnTypeObject, Columns: []spec.Column{
+						{Name: "-zip", Type: spec.ColumnTypeInt},
+						{Name: "geo", Type: spec.ColumnTypeObject},
+					}},
+					{Name: "owner", Type: spec.ColumnTypeLink},
+					{Name: "nickname", Type: spec.ColumnTypeString, RenamedFrom: "team"},
+				},
+			},
+			{Name: "users", Columns: []spec.Column{}},
+			{Name: "bad name", RenamedFrom: "users", Columns: []spec.Column{}},
+		},
+	}
+
+	require.Equal(t, []SchemaDiagnostic{
+		{Path: "formatVersion", Message: `unsupported formatVersion "2.0", expected "1.0"`},
+		{Path: "tables[0].columns[1]", Message: `duplicate column name "name"`},
+		{Path: "tables[0].columns[1]", Message: `column "name" of type text can't be unique`},
+		{Path: "tables[0].columns[2]", Message: `link column "team" references table "teams" that doesn't exist`},
+		{Path: "tables[0].columns[3].columns[0]", Message: `invalid column name "-zip"`},
+		{Path: "tables[0].columns[3].columns[1]", Message: `object column "geo" has no columns`},
+		{Path: "tables[0].columns[4]", Message: `link column "owner" doesn't say which table it links to`},
+		{Path: "tables[0].columns[5]", Message: `column "nickname" is renamed from "team", which is still in the table`},
+		{Path: "tables[1]", Message: `duplicate table name "users"`},
+		{Path: "tables[2]", Message: `invalid table name "bad name"`},
+		{Path: "tables[2]", Message: `table "bad name" is renamed from "users", which is still in the schema`},
+	}, ValidateSchema(schema))
+}
+
+func TestSchemaErrorDiagnostic(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, writeSettings(dir, SettingsFile{SchemaFileFormat: SettingsXata}))
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, "schema.xata"), []byte("table t {\n  a strin\n}"), 0644))
+
+	_, _, err := readSchemaFile(dir)
+	require.Equal(t, SchemaDiagnostic{Path: "2:5", Message: "unknown column type `strin`"}, schemaErrorDiagnostic(err))
+
+	require.NoError(t, writeSettings(dir, SettingsFile{SchemaFileFormat: SettingsJSON}))
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, "schema.json"), []byte("{"), 0644))
+
+	_, _, err = readSchemaFile(dir)
+	require.Equal(t, SchemaDiagnostic{Message: err.Error()}, schemaErrorDiagnostic(err))
+}
+
+func TestValidateSchemaNestedRename(t *testing.T) {
+	schema := spec.Schema{
+		FormatVersion: "1.0",
+		Tables: []spec.Table{
+			{Name: "users", Columns: []spec.Column{
+				{Name: "address", Type: spec.ColumnTypeObject, RenamedFrom: "location", Columns: []spec.Column{
+					{Name: "zipcode", Type: spec.ColumnTypeInt, RenamedFrom: "zip"},
+				}},
+			}},
+		},
+	}
+
+	require.Equal(t, []SchemaDiagnostic{
+		{Path: "tables[0].columns[0].columns[0]", Message: `column "zipcode" is nested in an object, so it can't be renamed with renamedFrom`},
+	}, ValidateSchema(schema))
+}
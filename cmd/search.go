@@ -0,0 +1,318 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/xataio/cli/client/spec"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/urfave/cli/v2"
+)
+
+// snippetLength is the number of characters of a long text shown around the
+// first match.
+const snippetLength = 80
+
+// searchTerms splits a search query in lower case words.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchesTerm returns true if a word matches one of the terms, allowing for
+// up to fuzziness typos or the word starting with the term. Like the search
+// engine, short terms allow fewer typos: none up to 2 characters and one up
+// to 5.
+func matchesTerm(word string, terms []string, fuzziness int) bool {
+	word = strings.ToLower(word)
+	for _, term := range terms {
+		allowed := fuzziness
+		if n := len([]rune(term)); n <= 2 {
+			allowed = 0
+		} else if n <= 5 && allowed > 1 {
+			allowed = 1
+		}
+		if strings.HasPrefix(word, term) || levenshtein(word, term) <= allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// highlightMatches marks the words of the text that match the search terms.
+// Long texts are cut to a snippet around the first match. It returns false if
+// no word matches.
+func highlightMatches(text string, terms []string, fuzziness int, mark func(a ...interface{}) string) (string, bool) {
+	type word struct {
+		start, end int
+		match      bool
+	}
+	runes := []rune(text)
+	words := []word{}
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+			i++
+		}
+		words = append(words, word{start, i, matchesTerm(string(runes[start:i]), terms, fuzziness)})
+	}
+
+	first := -1
+	for _, w := range words {
+		if w.match {
+			first = w.start
+			break
+		}
+	}
+	if first < 0 {
+		return text, false
+	}
+
+	from, to := 0, len(runes)
+	if len(runes) > snippetLength {
+		from = first - snippetLength/4
+		if from < 0 {
+			from = 0
+		}
+		to = from + snippetLength
+		if to > len(runes) {
+			to = len(runes)
+		}
+	}
+
+	var out strings.Builder
+	if from > 0 {
+		out.WriteString("…")
+	}
+	pos := from
+	for _, w := range words {
+		if !w.match || w.start < from || w.end > to {
+			continue
+		}
+		out.WriteString(string(runes[pos:w.start]))
+		out.WriteString(mark(string(runes[w.start:w.end])))
+		pos = w.end
+	}
+	out.WriteString(string(runes[pos:to]))
+	if to < len(runes) {
+		out.WriteString("…")
+	}
+	return out.String(), true
+}
+
+// flattenRecord returns the scalar values of a record by dotted path, the
+// values of objects being flattened.
+func flattenRecord(prefix string, values map[string]interface{}, flat map[string]interface{}) {
+	for name, value := range values {
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenRecord(prefix+name+".", nested, flat)
+			continue
+		}
+		flat[prefix+name] = value
+	}
+}
+
+// summarizeHit describes a search hit with the columns that match the search
+// terms, or the first columns of the record if no column obviously matches.
+func summarizeHit(record spec.Record, terms []string, fuzziness int, mark func(a ...interface{}) string) string {
+	flat := map[string]interface{}{}
+	flattenRecord("", record.AdditionalProperties, flat)
+	paths := make([]string, 0, len(flat))
+	for path := range flat {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	matches := []string{}
+	for _, path := range paths {
+		if highlighted, ok := highlightMatches(recordCellValue(flat[path]), terms, fuzziness, mark); ok {
+			matches = append(matches, fmt.Sprintf("%s: %s", path, highlighted))
+		}
+	}
+	if len(matches) > 0 {
+		return strings.Join(matches, "  ")
+	}
+
+	others := []string{}
+	for _, path := range paths {
+		value := recordCellValue(flat[path])
+		if value == "" {
+			continue
+		}
+		if runes := []rune(value); len(runes) > snippetLength/2 {
+			value = string(runes[:snippetLength/2]) + "…"
+		}
+		others = append(others, fmt.Sprintf("%s: %s", path, value))
+		if len(others) == 3 {
+			break
+		}
+	}
+	return strings.Join(others, "  ")
+}
+
+// groupHitsByTable groups the records by table, keeping the order in which
+// the tables first appear in the results.
+func groupHitsByTable(records []spec.Record) ([]string, map[string][]spec.Record) {
+	tables := []string{}
+	hits := map[string][]spec.Record{}
+	for _, record := range records {
+		table := ""
+		if record.Xata.Table != nil {
+			table = *record.Xata.Table
+		}
+		if _, ok := hits[table]; !ok {
+			tables = append(tables, table)
+		}
+		hits[table] = append(hits[table], record)
+	}
+	return tables, hits
+}
+
+func checkSearchResponse(resp *spec.SearchBranchResponse) error {
+	if resp.JSON401 != nil {
+		return ErrorUnauthorized{message: resp.JSON401.Message}
+	}
+	if resp.JSON400 != nil {
+		return fmt.Errorf("Error searching: %s", resp.JSON400.Message)
+	}
+	if resp.JSON404 != nil {
+		return fmt.Errorf("Error searching: %s", resp.JSON404.Message)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return fmt.Errorf("Error searching: %s", resp.Status())
+	}
+	if resp.JSON200 == nil {
+		return fmt.Errorf("Error searching: 200 OK unexpected response body")
+	}
+	return nil
+}
+
+// SearchCommand searches the records of all the tables of the branch, or of
+// the given tables.
+func SearchCommand(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return fmt.Errorf("Expected the terms to search for")
+	}
+	query := strings.Join(c.Args().Slice(), " ")
+	pick := c.Bool("pick")
+	if interactive, reason := isInteractiveWithReason(c); pick && !interactive {
+		return fmt.Errorf("--pick is interactive but %s", reason)
+	}
+
+	body := spec.SearchBranchJSONRequestBody{Query: query}
+	if tables := c.StringSlice("tables"); len(tables) > 0 {
+		body.Tables = &tables
+	}
+	fuzziness := 1
+	if c.IsSet("fuzziness") {
+		fuzziness = c.Int("fuzziness")
+		if fuzziness < 0 || fuzziness > 2 {
+			return fmt.Errorf("Invalid --fuzziness %d: must be 0, 1 or 2", fuzziness)
+		}
+		body.Fuzziness = &fuzziness
+	}
+
+	dbName, _, branch, err := getDBNameAndBranch(c)
+	if err != nil {
+		return err
+	}
+	client, err := getClientWithResponses(c)
+	if err != nil {
+		return err
+	}
+	dbBranch := spec.DBBranchNameParam(fmt.Sprintf("%s:%s", dbName, branch))
+	resp, err := client.SearchBranchWithResponse(c.Context, dbBranch, body)
+	if err != nil {
+		return fmt.Errorf("Error searching: %w", err)
+	}
+	if err := checkSearchResponse(resp); err != nil {
+		return err
+	}
+	records := resp.JSON200.Records
+
+	if c.Bool("json") && !pick {
+		out, err := json.Marshal(records)
+		if err != nil {
+			return err
+		}
+		return printJSON(c, out)
+	}
+	if len(records) == 0 {
+		fmt.Printf("No records found for %q.\n", query)
+		return nil
+	}
+
+	terms := searchTerms(query)
+	if pick {
+		return pickHit(c, client, dbBranch, records, terms, fuzziness)
+	}
+
+	bold := color.New(color.Bold)
+	mark := color.New(color.FgYellow, color.Bold)
+	if c.Bool("nocolor") {
+		bold.DisableColor()
+		mark.DisableColor()
+	}
+	tables, hits := groupHitsByTable(records)
+	for i, table := range tables {
+		if i > 0 {
+			fmt.Println()
+		}
+		bold.Printf("%s (%d)\n", table, len(hits[table]))
+		for _, record := range hits[table] {
+			fmt.Printf("  %s  %s\n", record.Id, summarizeHit(record, terms, fuzziness, mark.SprintFunc()))
+		}
+	}
+	return nil
+}
+
+// pickHit lets the user choose one of the hits and prints the full record,
+// as `records get` does: hits only have the columns that matched.
+func pickHit(c *cli.Context, client *spec.ClientWithResponses, dbBranch spec.DBBranchNameParam, records []spec.Record, terms []string, fuzziness int) error {
+	plain := fmt.Sprint
+	options := make([]string, 0, len(records))
+	for _, record := range records {
+		table := ""
+		if record.Xata.Table != nil {
+			table = *record.Xata.Table
+		}
+		options = append(options, fmt.Sprintf("%s/%s  %s", table, record.Id, summarizeHit(record, terms, fuzziness, plain)))
+	}
+
+	var chosen int
+	prompt := &survey.Select{
+		Message:  "Which record should I open?",
+		Options:  options,
+		PageSize: 15,
+	}
+	if err := survey.AskOne(prompt, &chosen); err != nil {
+		return err
+	}
+	hit := records[chosen]
+	if hit.Xata.Table == nil {
+		return fmt.Errorf("The search result doesn't say which table record [%s] is in", hit.Id)
+	}
+	resp, err := client.GetRecordWithResponse(c.Context, dbBranch,
+		spec.TableNameParam(*hit.Xata.Table), spec.RecordIDParam(hit.Id), spec.GetRecordJSONRequestBody{})
+	if err != nil {
+		return fmt.Errorf("Error getting record: %w", err)
+	}
+	return printResponse(c, resp, resp.Body, nil, func() error {
+		if resp.JSON200 == nil {
+			return fmt.Errorf("Unexpected server response %s", resp.Status())
+		}
+		printRecord(*resp.JSON200)
+		return nil
+	})
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/cli/client/spec"
+)
+
+func brackets(a ...interface{}) string {
+	return "[" + fmt.Sprint(a...) + "]"
+}
+
+func TestHighlightMatches(t *testing.T) {
+	terms := searchTerms("Alice, Smith")
+	require.Equal(t, []string{"alice", "smith"}, terms)
+
+	out, ok := highlightMatches("Alise Smyth and alice", terms, 1, brackets)
+	require.True(t, ok)
+	require.Equal(t, "[Alise] [Smyth] and [alice]", out)
+
+	out, ok = highlightMatches("Alise Smyth", terms, 0, brackets)
+	require.False(t, ok)
+	require.Equal(t, "Alise Smyth", out)
+
+	// short terms don't allow typos
+	_, ok = highlightMatches("is", searchTerms("it"), 2, brackets)
+	require.False(t, ok)
+	// prefixes match
+	out, _ = highlightMatches("Engineering team", searchTerms("engine"), 0, brackets)
+	require.Equal(t, "[Engineering] team", out)
+
+	long := strings.Repeat("lorem ipsum ", 20) + "needle" + strings.Repeat(" dolor sit", 20)
+	out, ok = highlightMatches(long, searchTerms("needle"), 0, brackets)
+	require.True(t, ok)
+	require.True(t, strings.HasPrefix(out, "…"))
+	require.True(t, strings.HasSuffix(out, "…"))
+	require.Contains(t, out, "[needle]")
+	require.Less(t, len([]rune(out)), snippetLength+10)
+}
+
+func TestSummarizeHit(t *testing.T) {
+	users := "users"
+	record := spec.Record{Id: "rec_1", AdditionalProperties: map[string]interface{}{
+		"name":    "Alice",
+		"email":   "alice@example.com",
+		"age":     float64(30),
+		"address": map[string]interface{}{"city": "Aliceville"},
+	}}
+	record.Xata.Table = &users
+
+	terms := searchTerms("alice")
+	require.Equal(t, "address.city: [Aliceville]  email: [alice]@example.com  name: [Alice]",
+		summarizeHit(record, terms, 1, brackets))
+	require.Equal(t, "address.city: Aliceville  age: 30  email: alice@example.com",
+		summarizeHit(record, searchTerms("zzz"), 1, brackets))
+
+	teams := "teams"
+	team := spec.Record{Id: "rec_2"}
+	team.Xata.Table = &teams
+	tables, hits := groupHitsByTable([]spec.Record{record, team, record})
+	require.Equal(t, []string{"users", "teams"}, tables)
+	require.Len(t, hits["users"], 2)
+	require.Len(t, hits["teams"], 1)
+}
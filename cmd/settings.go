@@ -17,6 +17,7 @@ const settingsFilename = "config.json"
 const (
 	SettingsYAML = "yaml"
 	SettingsJSON = "json"
+	SettingsXata = "xata"
 )
 
 type SettingsFile struct {
@@ -24,6 +25,14 @@ type SettingsFile struct {
 	DBName           string            `json:"dbName"`
 	WorkspaceID      string            `json:"workspaceID"`
 	Hooks            map[string]string `json:"hooks"`
+	// Branches holds the deploy policy of each database branch, by name
+	Branches map[string]BranchPolicy `json:"branches,omitempty"`
+}
+
+type BranchPolicy struct {
+	// DenyDestructive refuses migrations that drop tables or columns, or
+	// change column types, even with --allow-destructive
+	DenyDestructive bool `json:"denyDestructive,omitempty"`
 }
 
 func writeSettings(dir string, settings SettingsFile) error {
@@ -31,7 +40,7 @@ func writeSettings(dir string, settings SettingsFile) error {
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(path.Join(dir, settingsFilename), pretty.Pretty(file), 0644)
+	err = filesystem.WriteFileAtomic(path.Join(dir, settingsFilename), pretty.Pretty(file), 0644)
 	if err != nil {
 		return fmt.Errorf("writing config file: %w", err)
 	}
@@ -64,8 +73,21 @@ func ReadSettings(dir string) (*SettingsFile, error) {
 		return nil, fmt.Errorf("unmarshaling `%s/%s`: %w", dir, settingsFilename, err)
 	}
 
-	if settings.SchemaFileFormat != SettingsJSON && settings.SchemaFileFormat != SettingsYAML {
-		return nil, fmt.Errorf("the schemaFileFormat setting must be either `json` or `yaml`")
+	if settings.SchemaFileFormat != SettingsJSON && settings.SchemaFileFormat != SettingsYAML &&
+		settings.SchemaFileFormat != SettingsXata {
+		return nil, fmt.Errorf("the schemaFileFormat setting must be either `json`, `yaml` or `xata`")
 	}
 	return &settings, nil
 }
+
+// SchemaFilePath returns the path of the schema file for the configured format.
+func SchemaFilePath(dir string, settings *SettingsFile) string {
+	switch settings.SchemaFileFormat {
+	case SettingsYAML:
+		return path.Join(dir, "schema.yaml")
+	case SettingsXata:
+		return path.Join(dir, "schema.xata")
+	default:
+		return path.Join(dir, "schema.json")
+	}
+}
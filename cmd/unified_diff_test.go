@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := "l1\nl2\nl3\nl4\nl5\nl6\nl7\nl8\nl9\nl10\nl11\nl12\n"
+
+	tests := []struct {
+		name     string
+		from, to string
+		expected string
+	}{
+		{
+			name:     "no change",
+			from:     "a\nb\n",
+			to:       "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "pure add",
+			from:     "",
+			to:       "a\nb\n",
+			expected: "--- from\n+++ to\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:     "pure delete",
+			from:     "a\nb\n",
+			to:       "",
+			expected: "--- from\n+++ to\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "mixed hunks",
+			from: lines,
+			to:   "l1\nx2\nl3\nl4\nl5\nl6\nl7\nl8\nl9\nl10\nl12\n",
+			expected: "--- from\n+++ to\n" +
+				"@@ -1,5 +1,5 @@\n l1\n-l2\n+x2\n l3\n l4\n l5\n" +
+				"@@ -8,5 +8,4 @@\n l8\n l9\n l10\n-l11\n l12\n",
+		},
+		{
+			name: "close changes share a hunk",
+			from: lines,
+			to:   "l1\nl2\nl3\nx4\nl5\nl6\nl7\nl8\nx9\nl10\nl11\nl12\n",
+			expected: "--- from\n+++ to\n" +
+				"@@ -1,12 +1,12 @@\n l1\n l2\n l3\n-l4\n+x4\n l5\n l6\n l7\n l8\n-l9\n+x9\n l10\n l11\n l12\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, unifiedDiff("from", "to", test.from, test.to))
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -113,6 +114,20 @@ func getDBs(c *cli.Context, workspaceID string) ([]string, error) {
 	return res, nil
 }
 
+// getClientWithResponses returns a client for the workspace of the project.
+func getClientWithResponses(c *cli.Context) (*spec.ClientWithResponses, error) {
+	workspaceID, err := getWorkspaceID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := config.APIKey(c)
+	if err != nil {
+		return nil, err
+	}
+	return client.NewXataClientWithResponses(apiKey, workspaceID)
+}
+
 func gitGetRepoAndBranchName() (repo, branch string, err error) {
 	out, err := exec.Command("git", "rev-parse", "--show-toplevel", "--abbrev-ref", "HEAD").Output()
 	if err != nil {
@@ -142,6 +157,20 @@ func GitGetLastSHA() (sha string, err error) {
 	return strings.Trim(string(out), "\n"), nil
 }
 
+// GitShowFile returns the contents of filename, relative to the current
+// directory, at the given git revision.
+func GitShowFile(rev, filename string) ([]byte, error) {
+	out, err := exec.Command("git", "show", fmt.Sprintf("%s:./%s", rev, filepath.ToSlash(filename))).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("git show: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
 func GitHasLocalChanges(filename string) (bool, error) {
 	out, err := exec.Command("git", "-c", "color.status=false", "status", "-s", filename).Output()
 	if err != nil {
@@ -1,6 +1,9 @@
 package filesystem
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+)
 
 // FileExists returns true if the given file name exists
 func FileExists(filename string) (bool, error) {
@@ -15,3 +18,25 @@ func FileExists(filename string) (bool, error) {
 
 	return false, err
 }
+
+// WriteFileAtomic writes data to a temporary file in the same directory and
+// renames it over filename, so readers never see a partially written file.
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
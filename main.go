@@ -5,7 +5,6 @@ import (
 	"hash/maphash"
 	"math/rand"
 	"os"
-	"path"
 
 	"github.com/xataio/cli/buildvar"
 	"github.com/xataio/cli/cmd"
@@ -51,10 +50,7 @@ func getSchemaStatus(c *cli.Context) error {
 		return err
 	}
 
-	schemaFile := path.Join(dir, "schema.json")
-	if settings.SchemaFileFormat == cmd.SettingsYAML {
-		schemaFile = path.Join(dir, "schema.yaml")
-	}
+	schemaFile := cmd.SchemaFilePath(dir, settings)
 
 	exists, errr := filesystem.FileExists(schemaFile)
 	if errr != nil {
@@ -153,15 +149,32 @@ func main() {
 				},
 			},
 			{
-				Name:   "deploy",
-				Usage:  "Deploy database to xata.io",
-				Action: cmd.DeployCommand,
+				Name:        "deploy",
+				Usage:       "Deploy database to xata.io",
+				Action:      cmd.DeployCommand,
+				Subcommands: cmd.GetDeploySubcommands(),
 				Flags: []cli.Flag{
 					&cli.BoolFlag{
 						Name:    "force",
 						Aliases: []string{"f"},
 						Usage:   "Deploy without asking for confirmation.",
 					},
+					&cli.BoolFlag{
+						Name:  "no-verify-plan",
+						Usage: "Don't check the server migration plan against a plan computed locally before applying it.",
+					},
+					&cli.BoolFlag{
+						Name:  "allow-destructive",
+						Usage: "Allow --force to apply migrations that drop tables or columns, or change column types.",
+					},
+					&cli.BoolFlag{
+						Name:  "check",
+						Usage: "Only check for pending changes: exit with 0 if the branch is up to date, 2 if changes are pending and 1 on errors.",
+					},
+					&cli.StringFlag{
+						Name:  "markdown",
+						Usage: "With --check, write a Markdown summary of the changes to `FILE`.",
+					},
 				},
 			},
 			{
@@ -178,6 +191,36 @@ func main() {
 						Name:  "follow",
 						Usage: "Follow log history across branches.",
 					},
+					&cli.BoolFlag{
+						Name:  "oneline",
+						Usage: "Print each migration on a single line.",
+					},
+					&cli.BoolFlag{
+						Name:    "details",
+						Aliases: []string{"v"},
+						Usage:   "Print the definition of new columns and what changes in modified columns.",
+					},
+					&cli.StringFlag{
+						Name:  "since",
+						Usage: "Only show migrations created after `DATE` (2006-01-02 or RFC 3339).",
+					},
+					&cli.StringFlag{
+						Name:  "until",
+						Usage: "Only show migrations created up to `DATE` (2006-01-02, which includes the whole day, or RFC 3339).",
+					},
+					&cli.StringFlag{
+						Name:  "table",
+						Usage: "Only show migrations that change the `TABLE`.",
+					},
+					&cli.StringFlag{
+						Name:  "git-sha",
+						Usage: "Only show migrations deployed from this git `SHA` (a prefix is enough).",
+					},
+					&cli.IntFlag{
+						Name:    "limit",
+						Aliases: []string{"n"},
+						Usage:   "Show at most `N` migrations.",
+					},
 				},
 			},
 			{
@@ -209,6 +252,86 @@ func main() {
 						Usage:  "Get schema status",
 						Action: getSchemaStatus,
 					},
+					{
+						Name:   "show",
+						Usage:  "Show the schema of the branch, now or at a point of its migration history",
+						Action: cmd.SchemaShowCommand,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "at",
+								Usage: "Show the schema right after the migration with this `ID`",
+							},
+							&cli.StringFlag{
+								Name:  "at-git",
+								Usage: "Show the schema right after the latest migration deployed from this git `SHA`",
+							},
+							&cli.StringFlag{
+								Name:  "branch",
+								Usage: "Show the schema of this remote `BRANCH` (default: current branch)",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "The `FORMAT` to print the schema in: json, yaml or xata (default: the schema file format)",
+							},
+						},
+					},
+					{
+						Name:   "blame",
+						Usage:  "Show which migration last created or changed each table and column",
+						Action: cmd.SchemaBlameCommand,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "branch",
+								Usage: "Blame the schema of this remote `BRANCH` (default: current branch)",
+							},
+						},
+					},
+					{
+						Name:   "validate",
+						Usage:  "Validate the schema file without contacting the server",
+						Action: cmd.SchemaValidateCommand,
+					},
+					{
+						Name:   "diff",
+						Usage:  "Compare the schema file with a remote branch, a git revision or another file",
+						Action: cmd.SchemaDiffCommand,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "branch",
+								Usage: "Compare with the schema of this remote `BRANCH` (default: current branch)",
+							},
+							&cli.StringFlag{
+								Name:  "rev",
+								Usage: "Compare with the schema file at this git `REVISION`",
+							},
+							&cli.StringFlag{
+								Name:  "file",
+								Usage: "Compare with the schema in this `FILE` (.json, .yaml or .xata)",
+							},
+							&cli.BoolFlag{
+								Name:    "unified",
+								Aliases: []string{"u"},
+								Usage:   "Show a unified diff of the schema files",
+							},
+						},
+					},
+					{
+						Name:   "convert",
+						Usage:  "Convert the schema file to another format",
+						Action: cmd.SchemaConvertCommand,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "to",
+								Usage:    "The `FORMAT` to convert to: json, yaml or xata.",
+								Required: true,
+							},
+							&cli.BoolFlag{
+								Name:    "force",
+								Aliases: []string{"f"},
+								Usage:   "Overwrite an existing schema file in the target format.",
+							},
+						},
+					},
 				},
 			},
 			{
@@ -221,11 +344,197 @@ func main() {
 				Usage:       "Operations on branches",
 				Subcommands: cmd.GetBranchesSubcommands(),
 			},
+			{
+				Name:      "import",
+				Usage:     "Import a CSV or NDJSON file into a table",
+				ArgsUsage: "<table> <file>",
+				Action:    cmd.ImportCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "The format of the file, csv or ndjson (default: from the file extension).",
+					},
+					&cli.IntFlag{
+						Name:  "batch-size",
+						Usage: "Number of records sent per request.",
+						Value: 500,
+					},
+					&cli.StringFlag{
+						Name:  "delimiter",
+						Usage: "The field delimiter of CSV files.",
+						Value: ",",
+					},
+					&cli.StringFlag{
+						Name:  "multiple-separator",
+						Usage: "The separator of the values of multiple columns in CSV files.",
+						Value: ";",
+					},
+					&cli.StringFlag{
+						Name:  "reject-file",
+						Usage: "Write the rejected rows to `FILE` (default: <file>.rejects.ndjson).",
+					},
+					&cli.BoolFlag{
+						Name:  "create-table",
+						Usage: "Create the table if it doesn't exist, with columns inferred from the file, and add it to the schema file.",
+					},
+					&cli.IntFlag{
+						Name:  "sample-size",
+						Usage: "Number of rows sampled to infer the columns with --create-table.",
+						Value: 1000,
+					},
+					&cli.BoolFlag{
+						Name:    "force",
+						Aliases: []string{"f"},
+						Usage:   "Create the table without asking for confirmation.",
+					},
+				},
+			},
+			{
+				Name:        "records",
+				Usage:       "Get, insert, update and delete records",
+				Subcommands: cmd.GetRecordsSubcommands(),
+			},
 			{
 				Name:        "workspaces",
 				Usage:       "Operations on workspaces",
 				Subcommands: cmd.GetWorkspacesSubcommands(),
 			},
+			{
+				Name:      "query",
+				Usage:     "Query the records of a table",
+				ArgsUsage: "<table>",
+				Action:    cmd.QueryCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "where",
+						Usage: "Filter the records, e.g. 'age > 30 and team.name = \"core\"'.",
+					},
+					&cli.StringSliceFlag{
+						Name:  "columns",
+						Usage: "Comma separated list of columns to return (default: all).",
+					},
+					&cli.StringSliceFlag{
+						Name:  "sort",
+						Usage: "Comma separated list of columns to sort by, prefixed with - for descending order.",
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "Maximum number of records to return (up to 200, or any number with --all).",
+					},
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "Return all the matching records, following the pages, as one JSON document per line.",
+					},
+					&cli.IntFlag{
+						Name:  "page-size",
+						Usage: "Number of records fetched per request with --all (up to 200).",
+						Value: 200,
+					},
+				},
+			},
+			{
+				Name:      "search",
+				Usage:     "Search the records of all the tables of the branch",
+				ArgsUsage: "<terms>",
+				Action:    cmd.SearchCommand,
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:  "tables",
+						Usage: "Comma separated list of tables to search in (default: all).",
+					},
+					&cli.IntFlag{
+						Name:  "fuzziness",
+						Usage: "Number of typos allowed per word, 0 to 2 (default: 1).",
+					},
+					&cli.BoolFlag{
+						Name:    "pick",
+						Aliases: []string{"p"},
+						Usage:   "Choose one of the records found and print it in full.",
+					},
+				},
+			},
+			{
+				Name:      "export",
+				Usage:     "Export the records of a table to a CSV, NDJSON or JSON file",
+				ArgsUsage: "<table>",
+				Action:    cmd.ExportCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "The format of the export, csv, ndjson or json (default: from the --out extension, or ndjson).",
+					},
+					&cli.StringFlag{
+						Name:    "out",
+						Aliases: []string{"o"},
+						Usage:   "Write the records to `FILE` (default: stdout).",
+					},
+					&cli.StringFlag{
+						Name:  "where",
+						Usage: "Export only the records matching the filter, e.g. 'age > 30'.",
+					},
+					&cli.StringSliceFlag{
+						Name:  "columns",
+						Usage: "Comma separated list of columns to export (default: all).",
+					},
+					&cli.StringSliceFlag{
+						Name:  "sort",
+						Usage: "Comma separated list of columns to sort by, prefixed with - for descending order.",
+					},
+					&cli.StringFlag{
+						Name:  "delimiter",
+						Usage: "The field delimiter of CSV files.",
+						Value: ",",
+					},
+					&cli.StringFlag{
+						Name:  "multiple-separator",
+						Usage: "The separator of the values of multiple columns in CSV files.",
+						Value: ";",
+					},
+				},
+			},
+			{
+				Name:   "backup",
+				Usage:  "Back up the schema and the records of a branch to a tar or zip archive",
+				Action: cmd.BackupCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "out",
+						Aliases: []string{"o"},
+						Usage:   "Write the archive to `FILE` (default: <db>-<branch>-<time>.tar.gz).",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "The format of the archive, tar or zip (default: from the --out extension, or tar).",
+					},
+					&cli.StringFlag{
+						Name:  "branch",
+						Usage: "Back up this remote `BRANCH` (default: current branch)",
+					},
+				},
+			},
+			{
+				Name:      "restore",
+				Usage:     "Restore a backup archive into a new branch",
+				ArgsUsage: "<archive>",
+				Action:    cmd.RestoreCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "branch",
+						Usage:    "The new `BRANCH` to restore the backup into.",
+						Required: true,
+					},
+					&cli.IntFlag{
+						Name:  "concurrency",
+						Usage: "Number of records inserted in parallel.",
+						Value: 8,
+					},
+					&cli.BoolFlag{
+						Name:    "force",
+						Aliases: []string{"f"},
+						Usage:   "Restore without asking for confirmation.",
+					},
+				},
+			},
 			{
 				Name:   "random-data",
 				Usage:  "Insert random data in table.",